@@ -0,0 +1,193 @@
+package authorizer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// genesis hash used as previous hash of the first record in an audit log
+var genesisHash = strings.Repeat("0", sha256.Size*2)
+
+type AuditRecord struct {
+	Sequence  int                    `json:"sequence"`
+	Timestamp string                 `json:"timestamp"`
+	Input     json.RawMessage        `json:"input"`
+	Output    AccountOperationOutput `json:"output"`
+	PrevHash  string                 `json:"prev-hash"`
+	Hash      string                 `json:"hash"`
+}
+
+// AuditLog is an append-only file where every record is chained to the previous one by its hash.
+// It is safe for concurrent use, records being chained in the order they are appended.
+type AuditLog struct {
+	// held while a record is written, so concurrent appends cannot chain to the same record
+	mutex    sync.Mutex
+	writer   io.Writer
+	file     *os.File
+	sequence int
+	lastHash string
+	now      func() time.Time
+}
+
+func NewAuditLog(writer io.Writer) *AuditLog {
+	return &AuditLog{
+		writer:   writer,
+		sequence: 0,
+		lastHash: genesisHash,
+		now:      time.Now,
+	}
+}
+
+// OpenAuditLog opens (or creates) an audit log file and continues the chain of its last record
+func OpenAuditLog(path string) (*AuditLog, error) {
+	audit := NewAuditLog(nil)
+
+	if file, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var record AuditRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				file.Close()
+				return nil, fmt.Errorf("audit log %s is corrupted: %v", path, err)
+			}
+			audit.sequence = record.Sequence
+			audit.lastHash = record.Hash
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	audit.writer = file
+	audit.file = file
+
+	return audit, nil
+}
+
+func (audit *AuditLog) Close() error {
+	if audit == nil || audit.file == nil {
+		return nil
+	}
+	audit.mutex.Lock()
+	defer audit.mutex.Unlock()
+	return audit.file.Close()
+}
+
+// Append writes a new record for the given input line and its output
+func (audit *AuditLog) Append(input string, output AccountOperationOutput) error {
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, []byte(input)); err != nil {
+		return err
+	}
+
+	if output.Violations == nil {
+		output.Violations = make([]string, 0)
+	}
+
+	audit.mutex.Lock()
+	defer audit.mutex.Unlock()
+	record := AuditRecord{
+		Sequence:  audit.sequence + 1,
+		Timestamp: audit.now().UTC().Format(time.RFC3339Nano),
+		Input:     compacted.Bytes(),
+		Output:    output,
+		PrevHash:  audit.lastHash,
+	}
+
+	hash, err := hashAuditRecord(record)
+	if err != nil {
+		return err
+	}
+	record.Hash = hash
+
+	jsonData, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := audit.writer.Write(append(jsonData, '\n')); err != nil {
+		return err
+	}
+
+	audit.sequence = record.Sequence
+	audit.lastHash = record.Hash
+
+	return nil
+}
+
+func hashAuditRecord(record AuditRecord) (string, error) {
+	record.Hash = ""
+	jsonData, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	return hashAuditLine(jsonData), nil
+}
+
+func hashAuditLine(line []byte) string {
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+// unhashedAuditLine returns a record line as it was hashed, with an empty hash. The line is hashed as
+// written rather than marshalled again, so records written before a field was added still verify.
+func unhashedAuditLine(line []byte, hash string) ([]byte, bool) {
+	suffix := []byte(`,"hash":"` + hash + `"}`)
+	if !bytes.HasSuffix(line, suffix) {
+		return nil, false
+	}
+	unhashed := make([]byte, 0, len(line))
+	unhashed = append(unhashed, line[:len(line)-len(suffix)]...)
+	return append(unhashed, `,"hash":""}`...), true
+}
+
+// VerifyAuditLog validates the hash chain of an audit log and returns the number of records
+func VerifyAuditLog(reader io.Reader) (int, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	count := 0
+	prevHash := genesisHash
+
+	for scanner.Scan() {
+		line := count + 1
+
+		var record AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return count, fmt.Errorf("line %d: %v", line, err)
+		}
+		if record.Sequence != line {
+			return count, fmt.Errorf("line %d: expected sequence %d, got %d", line, line, record.Sequence)
+		}
+		if record.PrevHash != prevHash {
+			return count, fmt.Errorf("line %d: previous hash does not match record %d", line, line-1)
+		}
+
+		unhashed, ok := unhashedAuditLine(scanner.Bytes(), record.Hash)
+		if !ok || record.Hash != hashAuditLine(unhashed) {
+			return count, fmt.Errorf("line %d: record has been tampered", line)
+		}
+
+		prevHash = record.Hash
+		count++
+	}
+
+	return count, scanner.Err()
+}
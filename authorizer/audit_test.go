@@ -0,0 +1,148 @@
+package authorizer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestAuditLog(buffer *bytes.Buffer) *AuditLog {
+	audit := NewAuditLog(buffer)
+	audit.now = func() time.Time {
+		return time.Date(2019, 2, 13, 10, 0, 0, 0, time.UTC)
+	}
+	return audit
+}
+
+func TestAuditLogVerify(t *testing.T) {
+	var buffer bytes.Buffer
+	audit := newTestAuditLog(&buffer)
+	_ = audit.Append(`{"account": {"active-card": true, "available-limit": 100}}`, AccountOperationOutput{
+		Account: Account{ActiveCard: true, AvailableLimit: 100},
+	})
+	_ = audit.Append(`{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}`, AccountOperationOutput{
+		Account: Account{ActiveCard: true, AvailableLimit: 80},
+	})
+
+	expected := 2
+	result, err := VerifyAuditLog(&buffer)
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("VerifyAuditLog(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("VerifyAuditLog(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestAuditLogAppendConcurrent(t *testing.T) {
+	var buffer bytes.Buffer
+	audit := newTestAuditLog(&buffer)
+
+	var wait sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wait.Add(1)
+		go func() {
+			defer wait.Done()
+			for j := 0; j < 50; j++ {
+				_ = audit.Append(`{"account": {"active-card": true, "available-limit": 100}}`, AccountOperationOutput{
+					Account: Account{ActiveCard: true, AvailableLimit: 100},
+				})
+			}
+		}()
+	}
+	wait.Wait()
+
+	// every record chains to the one before it
+	expected := 400
+	result, err := VerifyAuditLog(&buffer)
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("VerifyAuditLog(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("VerifyAuditLog(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestAuditLogVerifyTampered(t *testing.T) {
+	var buffer bytes.Buffer
+	audit := newTestAuditLog(&buffer)
+	_ = audit.Append(`{"account": {"active-card": true, "available-limit": 100}}`, AccountOperationOutput{
+		Account: Account{ActiveCard: true, AvailableLimit: 100},
+	})
+	_ = audit.Append(`{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}`, AccountOperationOutput{
+		Account: Account{ActiveCard: true, AvailableLimit: 80},
+	})
+
+	tampered := strings.Replace(buffer.String(), `"available-limit":80`, `"available-limit":90`, 1)
+
+	expected := 1
+	result, err := VerifyAuditLog(strings.NewReader(tampered))
+
+	if err != nil && reflect.DeepEqual(expected, result) {
+		t.Logf("VerifyAuditLog(...) PASSED \nexpected: %v \nresult: %v %v", expected, result, err)
+	} else {
+		t.Errorf("VerifyAuditLog(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestAuditLogVerifyRemovedRecord(t *testing.T) {
+	var buffer bytes.Buffer
+	audit := newTestAuditLog(&buffer)
+	_ = audit.Append(`{"account": {"active-card": true, "available-limit": 100}}`, AccountOperationOutput{
+		Account: Account{ActiveCard: true, AvailableLimit: 100},
+	})
+	_ = audit.Append(`{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}`, AccountOperationOutput{
+		Account: Account{ActiveCard: true, AvailableLimit: 80},
+	})
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+
+	expected := 0
+	result, err := VerifyAuditLog(strings.NewReader(lines[1]))
+
+	if err != nil && reflect.DeepEqual(expected, result) {
+		t.Logf("VerifyAuditLog(...) PASSED \nexpected: %v \nresult: %v %v", expected, result, err)
+	} else {
+		t.Errorf("VerifyAuditLog(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+// TestAuditLogVerifyBeforeDecision verifies a log written before outputs had a decision, then continued
+func TestAuditLogVerifyBeforeDecision(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "audit", "before-decision.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "audit.log")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	audit, err := OpenAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = audit.Append(`{"transaction": {"merchant": "Subway", "amount": 10, "time": "2019-02-13T10:02:00.000Z"}}`, AccountOperationOutput{
+		Account: Account{ActiveCard: true, AvailableLimit: 70}, Decision: DecisionApproved,
+	})
+	_ = audit.Close()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	expected := 5
+	result, err := VerifyAuditLog(file)
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("VerifyAuditLog(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("VerifyAuditLog(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
@@ -0,0 +1,229 @@
+// Package authorizer authorizes account and transaction operations against a set of business rules.
+package authorizer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Operation is a single line of input, either an account or a transaction
+type Operation struct {
+	// ID is echoed in the output, optional
+	ID          string       `json:"id,omitempty"`
+	Account     *Account     `json:"account,omitempty"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+}
+
+// ErrInvalidOperation is wrapped by Apply errors about the operation itself, rather than the storage
+var ErrInvalidOperation = errors.New("invalid operation")
+
+// operationError is an error about one operation found while applying it, the operation leaving the
+// state untouched: it is ErrInvalidOperation as well as the error it wraps
+type operationError struct {
+	err error
+}
+
+func (err operationError) Error() string {
+	return err.err.Error()
+}
+
+func (err operationError) Unwrap() error {
+	return err.err
+}
+
+func (err operationError) Is(target error) bool {
+	return target == ErrInvalidOperation
+}
+
+type Options struct {
+	// Storage keeps account state and history, in memory when nil
+	Storage Storage
+	// AuditLog receives every decision when set
+	AuditLog *AuditLog
+	// Retention of the history, the largest rule window when zero
+	Retention time.Duration
+	// MaxHistory is the ceiling of transactions kept per account, no ceiling when zero
+	MaxHistory int
+	// Workers processing accounts in parallel on Run, sequential when lower than two
+	Workers int
+	// Errors receives the records that could not be decoded, discarded when nil
+	Errors io.Writer
+	// Rules configures the optional rules, DefaultRules when nil
+	Rules *Rules
+	// InputFormat and OutputFormat used by Run, ndjson when empty
+	InputFormat  string
+	OutputFormat string
+	// Clock driving the retention of the history, an EventClock when nil
+	Clock Clock
+	// Location transaction times are parsed in, Etc/GMT when nil
+	Location *time.Location
+}
+
+type Authorizer struct {
+	// serializes Apply calls, so operations of the same account are not interleaved
+	mutex    sync.Mutex
+	storage  *RetentionStorage
+	rules    Rules
+	clock    Clock
+	location *time.Location
+	audit    *AuditLog
+	workers  int
+	errors   io.Writer
+	input    string
+	output   string
+}
+
+func New(options Options) *Authorizer {
+	storage := options.Storage
+	if storage == nil {
+		storage = NewMemoryStorage()
+	}
+
+	rules := DefaultRules()
+	if options.Rules != nil {
+		rules = *options.Rules
+	}
+
+	retention := options.Retention
+	if retention <= 0 {
+		retention = defaultRetention(rules)
+	}
+
+	errorsWriter := options.Errors
+	if errorsWriter == nil {
+		errorsWriter = io.Discard
+	}
+
+	clock := options.Clock
+	if clock == nil {
+		clock = &EventClock{}
+	}
+	retentionStorage := NewRetentionStorage(storage, retention, options.MaxHistory)
+	retentionStorage.clock = clock
+
+	return &Authorizer{
+		storage:  retentionStorage,
+		rules:    rules,
+		clock:    clock,
+		location: options.Location,
+		audit:    options.AuditLog,
+		workers:  options.Workers,
+		errors:   errorsWriter,
+		input:    options.InputFormat,
+		output:   options.OutputFormat,
+	}
+}
+
+// fromOperation returns the AccountOperation or TransactionOperation described by an Operation,
+// once its values are validated, its time being parsed in location
+func fromOperation(operation Operation, location *time.Location) (interface{}, error) {
+	var value interface{}
+	switch true {
+	case operation.Account != nil && operation.Transaction == nil:
+		value = AccountOperation{ID: operation.ID, Account: *operation.Account}
+	case operation.Transaction != nil && operation.Account == nil:
+		transaction := *operation.Transaction
+		if data, ok := transaction.Time.(string); ok {
+			parsed, err := parseTime(data, location)
+			if err != nil {
+				return nil, &ValidationError{"transaction.time", err.Error()}
+			}
+			transaction.Time = parsed
+		}
+		value = TransactionOperation{ID: operation.ID, Transaction: transaction}
+	default:
+		return nil, errors.New("operation not valid")
+	}
+
+	if err := validateOperation(value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Apply authorizes a single operation against the current state, it is safe for concurrent use
+func (authorizer *Authorizer) Apply(operation Operation) (AccountOperationOutput, error) {
+	value, err := fromOperation(operation, authorizer.location)
+	if err != nil {
+		return AccountOperationOutput{}, fmt.Errorf("%w: %v", ErrInvalidOperation, err)
+	}
+
+	authorizer.mutex.Lock()
+	defer authorizer.mutex.Unlock()
+
+	output, err := apply(value, authorizer.storage, authorizer.rules)
+	if err != nil {
+		return output, err
+	}
+
+	if authorizer.audit != nil {
+		input, err := json.Marshal(operation)
+		if err != nil {
+			return output, err
+		}
+		if err := authorizer.audit.Append(string(input), output); err != nil {
+			return output, err
+		}
+	}
+
+	return output, nil
+}
+
+// Run authorizes every operation read from reader and writes the outputs to writer.
+// When ctx is done it stops reading, flushes the decisions already made and returns the ctx error.
+func (authorizer *Authorizer) Run(ctx context.Context, reader io.Reader, writer io.Writer) error {
+	decoder, err := newDecoder(authorizer.input, reader, authorizer.location)
+	if err != nil {
+		return err
+	}
+	encoder, err := NewEncoder(authorizer.output, writer)
+	if err != nil {
+		return err
+	}
+
+	err = authorizer.processParallel(ctx, decoder, encoder)
+	if flushErr := encoder.Flush(); flushErr != nil && err == nil {
+		err = flushErr
+	}
+
+	return err
+}
+
+// State returns the current status of an account
+func (authorizer *Authorizer) State(id string) (AccountStatus, error) {
+	if id == "" {
+		id = defaultAccountID
+	}
+	return authorizer.storage.GetAccount(id)
+}
+
+func (authorizer *Authorizer) Stats() RetentionStats {
+	return authorizer.storage.Stats()
+}
+
+// Now is the time of the clock of the authorizer, the latest transaction time in event mode
+func (authorizer *Authorizer) Now() time.Time {
+	return authorizer.clock.Now()
+}
+
+func (status AccountStatus) Account() Account {
+	return status.account
+}
+
+func (status AccountStatus) HasAccount() bool {
+	return status.hasAccount
+}
+
+// Held is the amount of the transactions of the account waiting for review
+func (status AccountStatus) Held() int {
+	return status.held
+}
+
+func (status AccountStatus) Profile() Profile {
+	return status.profile
+}
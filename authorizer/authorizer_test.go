@@ -0,0 +1,181 @@
+package authorizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAuthorizerApply(t *testing.T) {
+	authorize := New(Options{})
+
+	_, _ = authorize.Apply(Operation{Account: &Account{ActiveCard: true, AvailableLimit: 100}})
+
+	expected := AccountOperationOutput{
+		Account:    Account{ActiveCard: true, AvailableLimit: 80},
+		Violations: nil,
+		Decision:   DecisionApproved,
+	}
+	result, err := authorize.Apply(Operation{Transaction: &Transaction{
+		Merchant: "Burger King",
+		Amount:   20,
+		Time:     "2019-02-13T10:00:00.000Z",
+	}})
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("Authorizer.Apply(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Authorizer.Apply(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestAuthorizerApplyNotValid(t *testing.T) {
+	authorize := New(Options{})
+
+	_, err := authorize.Apply(Operation{})
+
+	if errors.Is(err, ErrInvalidOperation) {
+		t.Logf("Authorizer.Apply(...) PASSED \nexpected: error \nresult: %v", err)
+	} else {
+		t.Errorf("Authorizer.Apply(...) FAILED \nexpected: error \nresult: %v", err)
+	}
+}
+
+func TestAuthorizerRun(t *testing.T) {
+	input := `{"account": {"active-card": true, "available-limit": 100}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}
+{"transaction": {"merchant": "Habbib's", "amount": 90, "time": "2019-02-13T11:00:00.000Z"}}
+{"transaction": {"merchant": "McDonald's", "amount": 30, "time": "2019-02-13T12:00:00.000Z"}}`
+
+	expected := `{"account":{"active-card":true,"available-limit":100},"violations":[],"decision":"approved","id":"1"}
+{"account":{"active-card":true,"available-limit":80},"violations":[],"decision":"approved","id":"2"}
+{"account":{"active-card":true,"available-limit":80},"violations":["insufficient-limit"],"decision":"declined","id":"3"}
+{"account":{"active-card":true,"available-limit":50},"violations":[],"decision":"approved","id":"4"}
+`
+	var writer bytes.Buffer
+	err := New(Options{}).Run(context.Background(), strings.NewReader(input), &writer)
+	result := writer.String()
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("Authorizer.Run(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Authorizer.Run(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+// syncBuffer is a buffer that can be read while Run writes to it
+type syncBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (buffer *syncBuffer) Write(data []byte) (int, error) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+	return buffer.buffer.Write(data)
+}
+
+func (buffer *syncBuffer) String() string {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+	return buffer.buffer.String()
+}
+
+func testAuthorizerRunCancel(t *testing.T, workers int) {
+	reader, input := io.Pipe()
+	defer input.Close()
+
+	var writer syncBuffer
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- New(Options{Workers: workers}).Run(ctx, reader, &writer)
+	}()
+
+	// the input stays open, as a stream would
+	_, _ = io.WriteString(input, `{"account": {"active-card": true, "available-limit": 100}}`+"\n")
+	_, _ = io.WriteString(input, `{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}`+"\n")
+
+	expected := `{"account":{"active-card":true,"available-limit":100},"violations":[],"decision":"approved","id":"1"}
+{"account":{"active-card":true,"available-limit":80},"violations":[],"decision":"approved","id":"2"}
+`
+	for deadline := time.Now().Add(5 * time.Second); writer.String() != expected && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Authorizer.Run(...) did not stop")
+	}
+	result := writer.String()
+
+	if errors.Is(err, context.Canceled) && reflect.DeepEqual(expected, result) {
+		t.Logf("Authorizer.Run(...) PASSED \nexpected: %v \nresult: %v %v", expected, result, err)
+	} else {
+		t.Errorf("Authorizer.Run(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestAuthorizerRunCancel(t *testing.T) {
+	testAuthorizerRunCancel(t, 1)
+}
+
+func TestAuthorizerRunCancelParallel(t *testing.T) {
+	testAuthorizerRunCancel(t, 4)
+}
+
+func TestAuthorizerRunIDs(t *testing.T) {
+	input := `{"id": "a-1", "account": {"active-card": true, "available-limit": 100}}
+{"transaction": {"merchant": "Burger King", "amount": 20}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}
+{"id": "t-2", "transaction": {"merchant": "Habbib's", "amount": 90, "time": "2019-02-13T11:00:00.000Z"}}`
+
+	expected := `{"account":{"active-card":true,"available-limit":100},"violations":[],"decision":"approved","id":"a-1"}
+{"account":{"active-card":true,"available-limit":80},"violations":[],"decision":"approved","id":"3"}
+{"account":{"active-card":true,"available-limit":80},"violations":["insufficient-limit"],"decision":"declined","id":"t-2"}
+`
+
+	for _, workers := range []int{1, 4} {
+		var writer, audit bytes.Buffer
+		err := New(Options{Workers: workers, AuditLog: NewAuditLog(&audit)}).
+			Run(context.Background(), strings.NewReader(input), &writer)
+		result := writer.String()
+
+		var ids []string
+		for _, line := range strings.Split(strings.TrimSpace(audit.String()), "\n") {
+			var record AuditRecord
+			_ = json.Unmarshal([]byte(line), &record)
+			ids = append(ids, record.Output.ID)
+		}
+		expectedIDs := []string{"a-1", "3", "t-2"}
+
+		if err == nil && expected == result && reflect.DeepEqual(expectedIDs, ids) {
+			t.Logf("Authorizer.Run(workers=%d) PASSED \nexpected: %v %v \nresult: %v %v", workers, expected, expectedIDs, result, ids)
+		} else {
+			t.Errorf("Authorizer.Run(workers=%d) FAILED \nexpected: %v %v \nresult: %v %v %v", workers, expected, expectedIDs, result, ids, err)
+		}
+	}
+}
+
+func TestAuthorizerApplyID(t *testing.T) {
+	authorize := New(Options{})
+
+	result, err := authorize.Apply(Operation{ID: "a-1", Account: &Account{ActiveCard: true, AvailableLimit: 100}})
+	expected := AccountOperationOutput{Account: Account{ActiveCard: true, AvailableLimit: 100}, Decision: DecisionApproved, ID: "a-1"}
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("Authorizer.Apply(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Authorizer.Apply(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
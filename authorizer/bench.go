@@ -0,0 +1,93 @@
+package authorizer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// BenchOptions configures Bench
+type BenchOptions struct {
+	// Generate describes the stream of operations, generated before the clock starts
+	Generate GenerateOptions
+	// Rules decide the operations, nil means DefaultRules
+	Rules *Rules
+}
+
+// BenchResult is the time taken to decide a generated stream, operation by operation
+type BenchResult struct {
+	Operations int
+	Elapsed    time.Duration
+	P50        time.Duration
+	P99        time.Duration
+	Max        time.Duration
+}
+
+// Throughput returns the operations decided per second
+func (result BenchResult) Throughput() float64 {
+	if result.Elapsed <= 0 {
+		return 0
+	}
+	return float64(result.Operations) / result.Elapsed.Seconds()
+}
+
+func (result BenchResult) String() string {
+	return fmt.Sprintf("operations=%d elapsed=%v throughput=%.0f ops/s p50=%v p99=%v max=%v",
+		result.Operations, result.Elapsed.Round(time.Millisecond), result.Throughput(), result.P50, result.P99, result.Max)
+}
+
+// Bench decides a generated stream against in-memory state, timing every operation.
+// Decoding is left out of the timings, so that they measure the rules and the storage.
+func Bench(options BenchOptions) (BenchResult, error) {
+	rules := DefaultRules()
+	if options.Rules != nil {
+		rules = *options.Rules
+	}
+
+	var stream bytes.Buffer
+	if err := Generate(&stream, nil, options.Generate); err != nil {
+		return BenchResult{}, err
+	}
+	var operations []interface{}
+	scanner := bufio.NewScanner(&stream)
+	for scanner.Scan() {
+		operation, err := decode(scanner.Text(), nil)
+		if err != nil {
+			return BenchResult{}, err
+		}
+		operations = append(operations, operation)
+	}
+
+	storage := NewRetentionStorage(NewMemoryStorage(), defaultRetention(rules), 0)
+	latencies := make([]time.Duration, len(operations))
+	start := time.Now()
+	for i, operation := range operations {
+		began := time.Now()
+		if _, err := apply(operation, storage, rules); err != nil {
+			return BenchResult{}, err
+		}
+		latencies[i] = time.Since(began)
+	}
+	result := BenchResult{Operations: len(operations), Elapsed: time.Since(start)}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.P50 = percentile(latencies, 0.50)
+	result.P99 = percentile(latencies, 0.99)
+	result.Max = percentile(latencies, 1)
+	return result, nil
+}
+
+// percentile returns the smallest of the sorted values that p of the values are lower or equal to
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return sorted[i]
+}
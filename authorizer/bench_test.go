@@ -0,0 +1,34 @@
+package authorizer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 200; i++ {
+		sorted = append(sorted, time.Duration(i))
+	}
+
+	expected := []time.Duration{1, 100, 198, 200, 0}
+	result := []time.Duration{percentile(sorted, 0), percentile(sorted, 0.5), percentile(sorted, 0.99),
+		percentile(sorted, 1), percentile(nil, 0.5)}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("percentile(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("percentile(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestBench(t *testing.T) {
+	result, err := Bench(BenchOptions{Generate: GenerateOptions{Accounts: 3, Transactions: 200}})
+
+	if err == nil && result.Operations == 203 && result.P50 <= result.P99 && result.P99 <= result.Max && result.Throughput() > 0 {
+		t.Logf("Bench(...) PASSED \nexpected: %v operations \nresult: %v", 203, result)
+	} else {
+		t.Errorf("Bench(...) FAILED \nexpected: %v operations \nresult: %v %v", 203, result, err)
+	}
+}
@@ -0,0 +1,118 @@
+package authorizer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	ClockEvent = "event"
+	ClockWall  = "wall"
+)
+
+// Clock tells the authorizer what time it is, implementations must be safe for concurrent use
+type Clock interface {
+	Now() time.Time
+	// Tick is called with the time of every transaction applied, it returns the time it is for that transaction
+	Tick(event time.Time) time.Time
+}
+
+// NewClock returns the clock of a mode: event (driven by the transaction times) or wall
+func NewClock(mode string) (Clock, error) {
+	switch mode {
+	case "", ClockEvent:
+		return &EventClock{}, nil
+	case ClockWall:
+		return WallClock{}, nil
+	default:
+		return nil, fmt.Errorf("clock %q not valid", mode)
+	}
+}
+
+// EventClock is driven by the transactions: it is the time of the transaction being applied,
+// and now is the latest transaction time applied so far
+type EventClock struct {
+	mutex  sync.Mutex
+	latest time.Time
+}
+
+func (clock *EventClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.latest
+}
+
+func (clock *EventClock) Tick(event time.Time) time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	if event.After(clock.latest) {
+		clock.latest = event
+	}
+	return event
+}
+
+// WallClock is the time of the system, whatever the time of the transactions
+type WallClock struct{}
+
+func (WallClock) Now() time.Time {
+	return time.Now()
+}
+
+func (WallClock) Tick(time.Time) time.Time {
+	return time.Now()
+}
+
+// FakeClock only moves when told to, for tests
+type FakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (clock *FakeClock) Now() time.Time {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	return clock.now
+}
+
+func (clock *FakeClock) Tick(time.Time) time.Time {
+	return clock.Now()
+}
+
+func (clock *FakeClock) Set(now time.Time) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.now = now
+}
+
+func (clock *FakeClock) Advance(duration time.Duration) {
+	clock.mutex.Lock()
+	defer clock.mutex.Unlock()
+	clock.now = clock.now.Add(duration)
+}
+
+// defaultLocation is the location transaction times are parsed in when none is set, loaded once
+var defaultLocation struct {
+	sync.Once
+	location *time.Location
+}
+
+// parseLocation returns location, or Etc/GMT when it is nil
+func parseLocation(location *time.Location) *time.Location {
+	if location != nil {
+		return location
+	}
+	defaultLocation.Do(func() {
+		location, err := time.LoadLocation("Etc/GMT")
+		if err != nil {
+			// no time zone database on the system
+			location = time.UTC
+		}
+		defaultLocation.location = location
+	})
+	return defaultLocation.location
+}
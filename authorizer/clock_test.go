@@ -0,0 +1,131 @@
+package authorizer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := testHistory()[0].Time.(time.Time)
+	clock := NewFakeClock(start)
+
+	clock.Advance(time.Minute)
+	ticked := clock.Tick(start.Add(time.Hour))
+	clock.Set(start)
+
+	expected := []time.Time{start.Add(time.Minute), start}
+	result := []time.Time{ticked, clock.Now()}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("FakeClock PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("FakeClock FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestEventClock(t *testing.T) {
+	history := testHistory()
+	clock := &EventClock{}
+
+	// the time of a transaction is its own, now is the latest one seen
+	var result []time.Time
+	for _, transaction := range history {
+		result = append(result, clock.Tick(transaction.Time.(time.Time)))
+	}
+	result = append(result, clock.Now())
+
+	var expected []time.Time
+	for _, transaction := range history {
+		expected = append(expected, transaction.Time.(time.Time))
+	}
+	expected = append(expected, history[3].Time.(time.Time))
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("EventClock PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("EventClock FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestNewClock(t *testing.T) {
+	expected := []interface{}{&EventClock{}, WallClock{}, nil}
+
+	event, _ := NewClock(ClockEvent)
+	wall, _ := NewClock(ClockWall)
+	_, err := NewClock("lunar")
+	result := []interface{}{event, wall, nil}
+
+	if reflect.DeepEqual(expected, result) && err != nil {
+		t.Logf("NewClock(...) PASSED \nexpected: %v \nresult: %v %v", expected, result, err)
+	} else {
+		t.Errorf("NewClock(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestRetentionClock(t *testing.T) {
+	history := testHistory()
+	storage := NewRetentionStorage(NewMemoryStorage(), defaultRetention(Rules{}), 0)
+
+	// the clock is an hour past the history, so everything appended is already out of the window
+	storage.clock = NewFakeClock(history[3].Time.(time.Time).Add(time.Hour))
+	for _, transaction := range history {
+		_ = storage.AppendHistory(defaultAccountID, transaction)
+	}
+
+	expected := RetentionStats{Retention: 2 * time.Minute, Retained: 0, Evicted: 4}
+	result := storage.Stats()
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("RetentionStorage.Stats() PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("RetentionStorage.Stats() FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestAuthorizerNow(t *testing.T) {
+	history := testHistory()
+	authorize := New(Options{})
+	_, _ = authorize.Apply(Operation{Account: &Account{ActiveCard: true, AvailableLimit: 100}})
+	for _, transaction := range history {
+		transaction := transaction
+		_, _ = authorize.Apply(Operation{Transaction: &transaction})
+	}
+
+	expected := history[3].Time.(time.Time)
+	result := authorize.Now()
+
+	if expected.Equal(result) {
+		t.Logf("Authorizer.Now() PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Authorizer.Now() FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestOptionsLocation(t *testing.T) {
+	location := time.FixedZone("BRT", -3*60*60)
+	authorizers := []*Authorizer{New(Options{Location: location}), New(Options{})}
+
+	// each authorizer parses in its own location, the other one being left untouched
+	times := []string{"2019-02-13T08:00:00-03:00", "2019-02-13T11:00:00+00:00"}
+	expected := []string{"BRT", "Etc/GMT"}
+	var result []string
+	for i, authorize := range authorizers {
+		_, _ = authorize.Apply(Operation{Account: &Account{ActiveCard: true, AvailableLimit: 100}})
+		_, err := authorize.Apply(Operation{Transaction: &Transaction{Merchant: "Burger King", Amount: 20, Time: times[i]}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		history, err := authorize.storage.QueryHistory(defaultAccountID, time.Time{}, time.Time{})
+		if err != nil || len(history) != 1 {
+			t.Fatalf("QueryHistory(...) %v %v", history, err)
+		}
+		result = append(result, history[0].Time.(time.Time).Location().String())
+	}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("Options.Location PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Options.Location FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
@@ -0,0 +1,222 @@
+package authorizer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// columns of csv input, mapped by the header so they can be in any order
+const (
+	csvID             = "id"
+	csvType           = "type"
+	csvAccountID      = "account-id"
+	csvActiveCard     = "active-card"
+	csvAvailableLimit = "available-limit"
+	csvMerchant       = "merchant"
+	csvAmount         = "amount"
+	csvTime           = "time"
+	csvMCC            = "mcc"
+)
+
+var csvColumns = map[string]bool{
+	csvID: true, csvType: true, csvAccountID: true, csvActiveCard: true, csvAvailableLimit: true,
+	csvMerchant: true, csvAmount: true, csvTime: true, csvMCC: true,
+}
+
+var csvOutputHeader = []string{csvID, csvAccountID, csvActiveCard, csvAvailableLimit, "violations", "decision", "anomaly-score", "fraud-score", "review-id"}
+
+// csvDecoder reads one operation per row, the first row being the header
+type csvDecoder struct {
+	reader  *csv.Reader
+	columns map[string]int
+	line    int
+	// set once the header is not valid, rows cannot be read without it
+	done bool
+	// location times are parsed in
+	location *time.Location
+}
+
+func newCSVDecoder(reader io.Reader, location *time.Location) *csvDecoder {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	return &csvDecoder{reader: csvReader, location: location}
+}
+
+func (decoder *csvDecoder) Read() (interface{}, error) {
+	if decoder.done {
+		return nil, io.EOF
+	}
+	if decoder.columns == nil {
+		if err := decoder.readHeader(); err != nil {
+			decoder.done = true
+			return nil, err
+		}
+	}
+
+	record, err := decoder.read()
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (decoder *csvDecoder) readHeader() error {
+	header, err := decoder.read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return err
+		}
+		return fmt.Errorf("csv header not valid: %v", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !csvColumns[name] {
+			return fmt.Errorf("csv header has an unknown %s column", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns[csvType]; !ok {
+		return fmt.Errorf("csv header has no %s column", csvType)
+	}
+
+	decoder.columns = columns
+	return nil
+}
+
+// Line returns the line of the last record read
+func (decoder *csvDecoder) Line() int {
+	return decoder.line
+}
+
+// read reads the next row, keeping track of its line
+func (decoder *csvDecoder) read() ([]string, error) {
+	record, err := decoder.reader.Read()
+
+	var parseErr *csv.ParseError
+	switch {
+	case err == nil:
+		decoder.line, _ = decoder.reader.FieldPos(0)
+	case errors.As(err, &parseErr):
+		decoder.line = parseErr.StartLine
+	default:
+		decoder.line++
+	}
+	return record, err
+}
+
+func (decoder *csvDecoder) field(record []string, name string) string {
+	i, ok := decoder.columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func (decoder *csvDecoder) Decode(value interface{}) (interface{}, error) {
+	record := value.([]string)
+
+	// required as in the published schemas, a missing column being an empty value
+	kind := decoder.field(record, csvType)
+	for _, name := range requiredFields[kind] {
+		if decoder.field(record, name) == "" {
+			return nil, &ValidationError{kind + "." + name, "is required"}
+		}
+	}
+
+	switch kind {
+	case "account":
+		activeCard, err := strconv.ParseBool(decoder.field(record, csvActiveCard))
+		if err != nil {
+			return nil, fmt.Errorf("%s not valid: %v", csvActiveCard, err)
+		}
+		availableLimit, err := strconv.Atoi(decoder.field(record, csvAvailableLimit))
+		if err != nil {
+			return nil, fmt.Errorf("%s not valid: %v", csvAvailableLimit, err)
+		}
+
+		operation := AccountOperation{ID: decoder.field(record, csvID), Account: Account{
+			ID:             decoder.field(record, csvAccountID),
+			ActiveCard:     activeCard,
+			AvailableLimit: availableLimit,
+		}}
+		if err := validateOperation(operation); err != nil {
+			return nil, err
+		}
+		return operation, nil
+	case "transaction":
+		amount, err := strconv.Atoi(decoder.field(record, csvAmount))
+		if err != nil {
+			return nil, fmt.Errorf("%s not valid: %v", csvAmount, err)
+		}
+		parsed, err := parseTime(decoder.field(record, csvTime), decoder.location)
+		if err != nil {
+			return nil, fmt.Errorf("%s not valid: %v", csvTime, err)
+		}
+
+		operation := TransactionOperation{ID: decoder.field(record, csvID), Transaction: Transaction{
+			AccountID: decoder.field(record, csvAccountID),
+			Merchant:  decoder.field(record, csvMerchant),
+			Amount:    amount,
+			Time:      parsed,
+			MCC:       decoder.field(record, csvMCC),
+		}}
+		if err := validateOperation(operation); err != nil {
+			return nil, err
+		}
+		return operation, nil
+	default:
+		return nil, errors.New("operation not valid")
+	}
+}
+
+type csvEncoder struct {
+	writer      *csv.Writer
+	wroteHeader bool
+}
+
+func newCSVEncoder(writer io.Writer) *csvEncoder {
+	return &csvEncoder{writer: csv.NewWriter(writer)}
+}
+
+func (encoder *csvEncoder) Encode(output AccountOperationOutput) error {
+	if !encoder.wroteHeader {
+		encoder.wroteHeader = true
+		if err := encoder.writer.Write(csvOutputHeader); err != nil {
+			return err
+		}
+	}
+
+	return encoder.writer.Write([]string{
+		output.ID,
+		output.Account.ID,
+		strconv.FormatBool(output.Account.ActiveCard),
+		strconv.Itoa(output.Account.AvailableLimit),
+		strings.Join(output.Violations, ";"),
+		output.Decision,
+		formatScore(output.AnomalyScore),
+		formatScore(output.FraudScore),
+		output.ReviewID,
+	})
+}
+
+// formatScore writes a score in its shortest form, empty when zero
+func formatScore(score float64) string {
+	if score == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
+
+func (encoder *csvEncoder) Flush() error {
+	encoder.writer.Flush()
+	return encoder.writer.Error()
+}
@@ -0,0 +1,116 @@
+package authorizer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSVDecoder(t *testing.T) {
+	input := `time,amount,merchant,type,available-limit,active-card
+,,,account,100,true
+2019-02-13T10:00:00.000Z,20,Burger King,transaction,,`
+
+	decoder := newCSVDecoder(strings.NewReader(input), nil)
+	account, _ := decoder.Read()
+	transaction, _ := decoder.Read()
+
+	time, _ := parseTime("2019-02-13T10:00:00.000Z", nil)
+	expected := []interface{}{
+		AccountOperation{Account: Account{ActiveCard: true, AvailableLimit: 100}},
+		TransactionOperation{Transaction: Transaction{Merchant: "Burger King", Amount: 20, Time: time}},
+	}
+	var result []interface{}
+	for _, record := range []interface{}{account, transaction} {
+		operation, err := decoder.Decode(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, operation)
+	}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("csvDecoder.Decode(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("csvDecoder.Decode(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestCSVDecoderNotValid(t *testing.T) {
+	input := `type,amount,time
+transaction,twenty,2019-02-13T10:00:00.000Z`
+
+	decoder := newCSVDecoder(strings.NewReader(input), nil)
+	record, _ := decoder.Read()
+	_, err := decoder.Decode(record)
+
+	if err != nil {
+		t.Logf("csvDecoder.Decode(...) PASSED \nexpected: error \nresult: %v", err)
+	} else {
+		t.Errorf("csvDecoder.Decode(...) FAILED \nexpected: error \nresult: %v", err)
+	}
+}
+
+func TestCSVDecoderRequired(t *testing.T) {
+	inputs := map[string]string{
+		"no merchant column": "type,amount,time\ntransaction,20,2019-02-13T10:00:00.000Z",
+		"empty merchant":     "type,merchant,amount,time\ntransaction, ,20,2019-02-13T10:00:00.000Z",
+		"no limit column":    "type,active-card\naccount,true",
+	}
+	expected := map[string]string{
+		"no merchant column": "transaction.merchant: is required",
+		"empty merchant":     "transaction.merchant: is required",
+		"no limit column":    "account.available-limit: is required",
+	}
+
+	for name, input := range inputs {
+		decoder := newCSVDecoder(strings.NewReader(input), nil)
+		record, _ := decoder.Read()
+		_, err := decoder.Decode(record)
+		result := fmt.Sprint(err)
+
+		if expected[name] == result {
+			t.Logf("csvDecoder.Decode(%s) PASSED \nexpected: %v \nresult: %v", name, expected[name], result)
+		} else {
+			t.Errorf("csvDecoder.Decode(%s) FAILED \nexpected: %v \nresult: %v", name, expected[name], result)
+		}
+	}
+}
+
+func TestCSVDecoderUnknownColumn(t *testing.T) {
+	input := `type,amount,time,tip
+transaction,20,2019-02-13T10:00:00.000Z,2`
+
+	_, err := newCSVDecoder(strings.NewReader(input), nil).Read()
+
+	if err != nil {
+		t.Logf("csvDecoder.Read(...) PASSED \nexpected: error \nresult: %v", err)
+	} else {
+		t.Errorf("csvDecoder.Read(...) FAILED \nexpected: error \nresult: %v", err)
+	}
+}
+
+func TestRunCSV(t *testing.T) {
+	input := `type,id,account-id,active-card,available-limit,merchant,amount,time
+account,,1,true,100,,,
+transaction,t-1,1,,,Burger King,20,2019-02-13T10:00:00.000Z
+transaction,,1,,,Habbib's,90,2019-02-13T11:00:00.000Z`
+
+	expected := `id,account-id,active-card,available-limit,violations,decision,anomaly-score,fraud-score,review-id
+2,1,true,100,,approved,,,
+t-1,1,true,80,,approved,,,
+4,1,true,80,insufficient-limit,declined,,,
+`
+	var writer bytes.Buffer
+	err := New(Options{InputFormat: FormatCSV, OutputFormat: FormatCSV}).Run(context.Background(), strings.NewReader(input), &writer)
+	result := writer.String()
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("Authorizer.Run(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Authorizer.Run(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
@@ -0,0 +1,199 @@
+package authorizer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatColumnar writes features as a json object holding one array per column
+const FormatColumnar = "json"
+
+// FeatureOptions configures ExportFeatures
+type FeatureOptions struct {
+	InputFormat  string // ndjson (default), csv or protobuf
+	OutputFormat string // csv (default) or json
+	// Rules decide the transactions, nil means DefaultRules
+	Rules *Rules
+	// Errors receives the operations that are not valid, discarded when nil
+	Errors io.Writer
+}
+
+// featureRow is a transaction with the features it was decided with and the decision
+type featureRow struct {
+	transaction Transaction
+	account     Account // status of the account before the transaction
+	features    Features
+	// inputs of the card-testing and structuring rules, zero when they are disabled
+	cardTestingMerchants int
+	structuringSum       int
+	output               AccountOperationOutput
+}
+
+type featureColumn struct {
+	name  string
+	value func(row featureRow) interface{}
+}
+
+var featureColumns = []featureColumn{
+	{"id", func(row featureRow) interface{} { return row.output.ID }},
+	{"account-id", func(row featureRow) interface{} { return row.transaction.AccountID }},
+	{"merchant", func(row featureRow) interface{} { return row.transaction.Merchant }},
+	{"amount", func(row featureRow) interface{} { return row.transaction.Amount }},
+	{"time", func(row featureRow) interface{} { return row.transaction.Time.(time.Time).Format(time.RFC3339) }},
+	{"active-card", func(row featureRow) interface{} { return row.account.ActiveCard }},
+	{"available-limit", func(row featureRow) interface{} { return row.account.AvailableLimit }},
+	{"amount-ratio", func(row featureRow) interface{} { return row.features.AmountRatio }},
+	{"seconds-since-last", func(row featureRow) interface{} { return row.features.SecondsSinceLast }},
+	{"new-merchant", func(row featureRow) interface{} { return row.features.NewMerchant }},
+	{"recent-transactions", func(row featureRow) interface{} { return row.features.RecentTransactions }},
+	{"recent-merchant-transactions", func(row featureRow) interface{} { return row.features.RecentMerchantTransactions }},
+	{"card-testing-merchants", func(row featureRow) interface{} { return row.cardTestingMerchants }},
+	{"structuring-sum", func(row featureRow) interface{} { return row.structuringSum }},
+	{"anomaly-score", func(row featureRow) interface{} { return row.output.AnomalyScore }},
+	{"fraud-score", func(row featureRow) interface{} { return row.output.FraudScore }},
+	{"violations", func(row featureRow) interface{} { return append([]string{}, row.output.Violations...) }},
+	{"approved", func(row featureRow) interface{} { return row.output.Decision == DecisionApproved }},
+}
+
+// ExportFeatures replays the operations read from reader and writes, for every transaction, its features
+// computed as the rules compute them and the decision made, returning how many transactions were written
+func ExportFeatures(reader io.Reader, writer io.Writer, options FeatureOptions) (int, error) {
+	decoder, err := NewDecoder(options.InputFormat, reader)
+	if err != nil {
+		return 0, err
+	}
+	var write func(rows []featureRow, writer io.Writer) error
+	switch options.OutputFormat {
+	case "", FormatCSV:
+		write = writeFeaturesCSV
+	case FormatColumnar:
+		write = writeFeaturesColumnar
+	default:
+		return 0, fmt.Errorf("output format %q not valid", options.OutputFormat)
+	}
+	errs := options.Errors
+	if errs == nil {
+		errs = io.Discard
+	}
+	rules := DefaultRules()
+	if options.Rules != nil {
+		rules = *options.Rules
+	}
+
+	storage := NewRetentionStorage(NewMemoryStorage(), defaultRetention(rules), 0)
+	var rows []featureRow
+	for ordinal := 1; ; ordinal++ {
+		value, err := decoder.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line := recordLine(decoder, ordinal)
+		if err != nil {
+			fmt.Fprintf(errs, "line %d: %v\n", line, err)
+			continue
+		}
+		operation, err := decoder.Decode(value)
+		if err != nil {
+			fmt.Fprintf(errs, "line %d: %v\n", line, err)
+			continue
+		}
+		operation = withLineID(operation, line)
+
+		transaction, ok := operation.(TransactionOperation)
+		if !ok {
+			if _, err := apply(operation, storage, rules); err != nil {
+				return len(rows), err
+			}
+			continue
+		}
+
+		id := accountID(transaction)
+		status, err := storage.GetAccount(id)
+		if err != nil {
+			return len(rows), err
+		}
+		features, err := transactionFeatures(status, storage, id, transaction.Transaction)
+		if err != nil {
+			return len(rows), err
+		}
+		merchants, err := cardTestingMerchants(rules.CardTesting, storage, id, transaction.Transaction)
+		if err != nil {
+			return len(rows), err
+		}
+		sum, err := structuringSum(rules.Structuring, storage, id, transaction.Transaction)
+		if err != nil {
+			return len(rows), err
+		}
+		output, err := apply(operation, storage, rules)
+		if err != nil {
+			return len(rows), err
+		}
+		rows = append(rows, featureRow{transaction: transaction.Transaction, account: status.account, features: features,
+			cardTestingMerchants: merchants, structuringSum: sum, output: output})
+	}
+
+	return len(rows), write(rows, writer)
+}
+
+func writeFeaturesCSV(rows []featureRow, writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+	record := make([]string, len(featureColumns))
+	for i, column := range featureColumns {
+		record[i] = column.name
+	}
+	if err := csvWriter.Write(record); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		for i, column := range featureColumns {
+			switch value := column.value(row).(type) {
+			case string:
+				record[i] = value
+			case int:
+				record[i] = strconv.Itoa(value)
+			case float64:
+				record[i] = strconv.FormatFloat(value, 'f', -1, 64)
+			case bool:
+				record[i] = strconv.FormatBool(value)
+			case []string:
+				record[i] = strings.Join(value, ";")
+			}
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// writeFeaturesColumnar writes a json object with the values of every column, in column order
+func writeFeaturesColumnar(rows []featureRow, writer io.Writer) error {
+	buffered := bufio.NewWriter(writer)
+	buffered.WriteString("{")
+	for i, column := range featureColumns {
+		values := make([]interface{}, len(rows))
+		for j, row := range rows {
+			values[j] = column.value(row)
+		}
+		name, _ := json.Marshal(column.name)
+		jsonData, err := json.Marshal(values)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			buffered.WriteString(",")
+		}
+		fmt.Fprintf(buffered, "%s:%s", name, jsonData)
+	}
+	buffered.WriteString("}\n")
+	return buffered.Flush()
+}
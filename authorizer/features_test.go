@@ -0,0 +1,82 @@
+package authorizer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const featuresInput = `{"account": {"active-card": true, "available-limit": 100}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}
+{"transaction": {"merchant": "Burger King", "amount": 20}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:30.000Z"}}`
+
+func TestExportFeatures(t *testing.T) {
+	expected := `id,account-id,merchant,amount,time,active-card,available-limit,amount-ratio,seconds-since-last,new-merchant,recent-transactions,recent-merchant-transactions,card-testing-merchants,structuring-sum,anomaly-score,fraud-score,violations,approved
+2,,Burger King,20,2019-02-13T10:00:00Z,true,100,0.2,600,1,0,0,0,0,0,0,,true
+4,,Burger King,20,2019-02-13T10:00:30Z,true,80,0.25,30,0,1,1,0,0,0,0,doubled-transaction,false
+`
+	var writer, errs bytes.Buffer
+	count, err := ExportFeatures(strings.NewReader(featuresInput), &writer, FeatureOptions{Errors: &errs})
+	result := writer.String()
+
+	if err == nil && count == 2 && expected == result && errs.String() == "line 3: transaction.time: is required\n" {
+		t.Logf("ExportFeatures(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("ExportFeatures(...) FAILED \nexpected: %v \nresult: %v %v %v %v", expected, result, count, errs.String(), err)
+	}
+}
+
+func TestExportFeaturesColumnar(t *testing.T) {
+	expected := `{"id":["2","4"],"account-id":["",""],"merchant":["Burger King","Burger King"],"amount":[20,20],` +
+		`"time":["2019-02-13T10:00:00Z","2019-02-13T10:00:30Z"],"active-card":[true,true],"available-limit":[100,80],` +
+		`"amount-ratio":[0.2,0.25],"seconds-since-last":[600,30],"new-merchant":[1,0],"recent-transactions":[0,1],` +
+		`"recent-merchant-transactions":[0,1],"card-testing-merchants":[0,0],"structuring-sum":[0,0],` +
+		`"anomaly-score":[0,0],"fraud-score":[0,0],` +
+		`"violations":[[],["doubled-transaction"]],"approved":[true,false]}` + "\n"
+
+	var writer bytes.Buffer
+	_, err := ExportFeatures(strings.NewReader(featuresInput), &writer, FeatureOptions{OutputFormat: FormatColumnar})
+	result := writer.String()
+
+	if err == nil && expected == result {
+		t.Logf("ExportFeatures(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("ExportFeatures(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestExportFeaturesRuleInputs(t *testing.T) {
+	input := `{"account": {"active-card": true, "available-limit": 1000}}
+{"transaction": {"merchant": "Shop A", "amount": 1, "time": "2019-02-13T10:00:00.000Z"}}
+{"transaction": {"merchant": "Shop B", "amount": 2, "time": "2019-02-13T10:03:00.000Z"}}
+{"transaction": {"merchant": "Shop A", "amount": 300, "time": "2019-02-13T10:05:00.000Z"}}
+{"transaction": {"merchant": "Shop A", "amount": 300, "time": "2019-02-13T10:10:00.000Z"}}
+{"transaction": {"merchant": "Shop A", "amount": 150, "time": "2019-02-13T10:15:00.000Z"}}`
+
+	// the fourth is declined as structuring, so the fifth does not add it up
+	expected := `"card-testing-merchants":[1,2,0,0,0],"structuring-sum":[1,2,301,601,451]`
+	rules := DefaultRules()
+	rules.CardTesting = testCardTestingRule
+	rules.Structuring = testStructuringRule
+	var writer bytes.Buffer
+	_, err := ExportFeatures(strings.NewReader(input), &writer, FeatureOptions{OutputFormat: FormatColumnar, Rules: &rules})
+	result := writer.String()
+
+	if err == nil && strings.Contains(result, expected) {
+		t.Logf("ExportFeatures(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("ExportFeatures(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestExportFeaturesFormatNotValid(t *testing.T) {
+	var writer bytes.Buffer
+	_, err := ExportFeatures(strings.NewReader(featuresInput), &writer, FeatureOptions{OutputFormat: FormatTable})
+
+	if err != nil && writer.Len() == 0 {
+		t.Logf("ExportFeatures(...) PASSED \nexpected: error \nresult: %v", err)
+	} else {
+		t.Errorf("ExportFeatures(...) FAILED \nexpected: error \nresult: %v", err)
+	}
+}
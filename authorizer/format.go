@@ -0,0 +1,168 @@
+package authorizer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	FormatNDJSON   = "ndjson"
+	FormatCSV      = "csv"
+	FormatTable    = "table"
+	FormatProtobuf = "protobuf"
+)
+
+// Decoder reads raw records from an input and decodes them into operations
+type Decoder interface {
+	// Read returns the next raw record, io.EOF when the input is exhausted.
+	// Other errors concern a single record, reading can go on after them.
+	Read() (interface{}, error)
+	// Decode converts a raw record into an AccountOperation or a TransactionOperation,
+	// it is called concurrently
+	Decode(record interface{}) (interface{}, error)
+}
+
+// Encoder writes outputs in a given format
+type Encoder interface {
+	Encode(output AccountOperationOutput) error
+	Flush() error
+}
+
+func NewDecoder(format string, reader io.Reader) (Decoder, error) {
+	return newDecoder(format, reader, nil)
+}
+
+// newDecoder returns a decoder parsing the times of the operations in location, Etc/GMT when it is nil
+func newDecoder(format string, reader io.Reader, location *time.Location) (Decoder, error) {
+	switch format {
+	case "", FormatNDJSON:
+		return newJSONDecoder(reader, location), nil
+	case FormatCSV:
+		return newCSVDecoder(reader, location), nil
+	case FormatProtobuf:
+		return &protobufDecoder{reader: bufio.NewReader(reader)}, nil
+	default:
+		return nil, fmt.Errorf("input format %q not valid", format)
+	}
+}
+
+func NewEncoder(format string, writer io.Writer) (Encoder, error) {
+	switch format {
+	case "", FormatNDJSON:
+		return &jsonEncoder{writer: bufio.NewWriter(writer)}, nil
+	case FormatCSV:
+		return newCSVEncoder(writer), nil
+	case FormatTable:
+		return &tableEncoder{writer: bufio.NewWriter(writer)}, nil
+	case FormatProtobuf:
+		return &protobufEncoder{writer: bufio.NewWriter(writer)}, nil
+	default:
+		return nil, fmt.Errorf("output format %q not valid", format)
+	}
+}
+
+// jsonDecoder reads one json operation per line
+type jsonDecoder struct {
+	scanner  *bufio.Scanner
+	reported bool
+	line     int
+	location *time.Location
+}
+
+func newJSONDecoder(reader io.Reader, location *time.Location) *jsonDecoder {
+	return &jsonDecoder{scanner: bufio.NewScanner(reader), location: location}
+}
+
+func (decoder *jsonDecoder) Read() (interface{}, error) {
+	if decoder.scanner.Scan() {
+		decoder.line++
+		return decoder.scanner.Text(), nil
+	}
+
+	// a scanner stops on its first error, report it once
+	if err := decoder.scanner.Err(); err != nil && !decoder.reported {
+		decoder.reported = true
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func (decoder *jsonDecoder) Line() int {
+	return decoder.line
+}
+
+func (decoder *jsonDecoder) Decode(record interface{}) (interface{}, error) {
+	return decode(record.(string), decoder.location)
+}
+
+type jsonEncoder struct {
+	writer *bufio.Writer
+}
+
+func (encoder *jsonEncoder) Encode(output AccountOperationOutput) error {
+	_, err := fmt.Fprintln(encoder.writer, formatOutput(output))
+	return err
+}
+
+func (encoder *jsonEncoder) Flush() error {
+	return encoder.writer.Flush()
+}
+
+func formatOutput(item AccountOperationOutput) string {
+	if item.Violations == nil {
+		item.Violations = make([]string, 0)
+	}
+	jsonData, _ := json.Marshal(item)
+	return string(jsonData)
+}
+
+// tableEncoder writes outputs as aligned columns for humans
+type tableEncoder struct {
+	writer      *bufio.Writer
+	wroteHeader bool
+}
+
+const tableRow = "%-8s %-12s %-11s %15s  %-8s  %s\n"
+
+func (encoder *tableEncoder) Encode(output AccountOperationOutput) error {
+	if !encoder.wroteHeader {
+		encoder.wroteHeader = true
+		if _, err := fmt.Fprintf(encoder.writer, tableRow, "ID", "ACCOUNT", "ACTIVE-CARD", "AVAILABLE-LIMIT", "DECISION", "VIOLATIONS"); err != nil {
+			return err
+		}
+	}
+
+	violations := strings.Join(output.Violations, ", ")
+	if violations == "" {
+		violations = "-"
+	}
+	_, err := fmt.Fprintf(encoder.writer, tableRow, output.ID, output.Account.ID, fmt.Sprint(output.Account.ActiveCard),
+		fmt.Sprint(output.Account.AvailableLimit), output.Decision, violations)
+	return err
+}
+
+func (encoder *tableEncoder) Flush() error {
+	return encoder.writer.Flush()
+}
+
+// auditInput returns the json line as read, or the json of the operation for other formats
+func auditInput(record interface{}, operation interface{}) (string, error) {
+	if line, ok := record.(string); ok {
+		return line, nil
+	}
+
+	value := Operation{ID: operationID(operation)}
+	switch operation := operation.(type) {
+	case AccountOperation:
+		value.Account = &operation.Account
+	case TransactionOperation:
+		value.Transaction = &operation.Transaction
+	}
+
+	jsonData, err := json.Marshal(value)
+	return string(jsonData), err
+}
@@ -0,0 +1,42 @@
+package authorizer
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestTableEncoder(t *testing.T) {
+	var buffer bytes.Buffer
+	encoder, _ := NewEncoder(FormatTable, &buffer)
+	_ = encoder.Encode(AccountOperationOutput{Account: Account{ActiveCard: true, AvailableLimit: 100}, Decision: DecisionApproved, ID: "1"})
+	_ = encoder.Encode(AccountOperationOutput{
+		Account:    Account{ActiveCard: true, AvailableLimit: 100},
+		Violations: []string{InsufficientLimit, HighFrequencySmallInterval},
+		Decision:   DecisionDeclined,
+		ID:         "op-2",
+	})
+	_ = encoder.Flush()
+
+	expected := "ID       ACCOUNT      ACTIVE-CARD AVAILABLE-LIMIT  DECISION  VIOLATIONS\n" +
+		"1                     true                    100  approved  -\n" +
+		"op-2                  true                    100  declined  insufficient-limit, high-frequency-small-interval\n"
+	result := buffer.String()
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("tableEncoder.Encode(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("tableEncoder.Encode(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestFormatNotValid(t *testing.T) {
+	_, decoderErr := NewDecoder("xml", nil)
+	_, encoderErr := NewEncoder("xml", nil)
+
+	if decoderErr != nil && encoderErr != nil {
+		t.Logf("NewDecoder/NewEncoder(...) PASSED \nexpected: error \nresult: %v %v", decoderErr, encoderErr)
+	} else {
+		t.Errorf("NewDecoder/NewEncoder(...) FAILED \nexpected: error \nresult: %v %v", decoderErr, encoderErr)
+	}
+}
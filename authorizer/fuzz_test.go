@@ -0,0 +1,130 @@
+package authorizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// knownViolations are every violation an output may carry
+var knownViolations = map[string]bool{
+	AccountNotInitialized:      true,
+	AccountAlreadyInitialized:  true,
+	CardNotActive:              true,
+	DoubledTransaction:         true,
+	InsufficientLimit:          true,
+	HighFrequencySmallInterval: true,
+	CardTestingSuspected:       true,
+	StructuringSuspected:       true,
+	AnomalousTransaction:       true,
+	HighFraudScore:             true,
+}
+
+// fuzzSeeds start the fuzz targets, the inputs that once crashed are kept in testdata/fuzz
+var fuzzSeeds = []string{
+	`{"account": {"active-card": true, "available-limit": 100}}`,
+	`{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}`,
+	`{"id": "t-1", "transaction": {"account-id": "a", "merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z", "mcc": "5814"}}`,
+	`{"account": {"active-card": true, "available-limit": -1}}`,
+	`{"account": null, "transaction": null}`,
+	`[]`,
+}
+
+// FuzzDecode checks that any line is either rejected or decoded into an operation that can be applied
+func FuzzDecode(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		operation, err := decode(line, nil)
+		if err != nil {
+			return
+		}
+
+		switch value := operation.(type) {
+		case AccountOperation:
+		case TransactionOperation:
+			if _, ok := value.Transaction.Time.(time.Time); !ok {
+				t.Fatalf("decode(%q) time is %T", line, value.Transaction.Time)
+			}
+		default:
+			t.Fatalf("decode(%q) operation is %T", line, operation)
+		}
+
+		if _, err := apply(operation, NewMemoryStorage(), DefaultRules()); err != nil {
+			t.Fatalf("apply(decode(%q)) error %v", line, err)
+		}
+	})
+}
+
+// FuzzUnmarshalOperation checks that any protobuf message is either rejected or decoded
+func FuzzUnmarshalOperation(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		var operation Operation
+		if json.Unmarshal([]byte(seed), &operation) == nil {
+			if data, err := MarshalOperation(operation); err == nil {
+				f.Add(data)
+			}
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		operation, err := UnmarshalOperation(data)
+		if err != nil {
+			return
+		}
+		_, _ = fromOperation(operation, nil)
+	})
+}
+
+// FuzzRun checks the invariants of the decisions made on a sequence of operations: no violation
+// out of the known set, an approved operation only without violations (with the default policy)
+// and never leaving a negative limit, and the same outputs
+// whether accounts are processed sequentially or in parallel
+func FuzzRun(f *testing.F) {
+	f.Add(strings.Join(fuzzSeeds, "\n"))
+	f.Add(testOperations(3, 5))
+	f.Add(`{"account": {"active-card": true, "available-limit": 100}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:01.000Z"}}
+{"transaction": {"merchant": "Habbib's", "amount": 90, "time": "2019-02-13T10:00:02.000Z"}}
+{"transaction": {"merchant": "Habbib's", "amount": 1, "time": "2019-02-13T10:00:03.000Z"}}`)
+
+	f.Fuzz(func(t *testing.T, input string) {
+		var sequential, parallel bytes.Buffer
+		if err := New(Options{Workers: 1}).Run(context.Background(), strings.NewReader(input), &sequential); err != nil {
+			t.Fatalf("Authorizer.Run(...) error %v", err)
+		}
+		if err := New(Options{Workers: 4}).Run(context.Background(), strings.NewReader(input), &parallel); err != nil {
+			t.Fatalf("Authorizer.Run(...) error %v", err)
+		}
+		if sequential.String() != parallel.String() {
+			t.Fatalf("Authorizer.Run(...) not deterministic \nsequential: %v \nparallel: %v", sequential.String(), parallel.String())
+		}
+
+		for _, line := range strings.Split(strings.TrimSpace(sequential.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var output AccountOperationOutput
+			if err := json.Unmarshal([]byte(line), &output); err != nil {
+				t.Fatalf("output %q not valid: %v", line, err)
+			}
+			for _, violation := range output.Violations {
+				if !knownViolations[violation] {
+					t.Fatalf("output %q has unknown violation %s", line, violation)
+				}
+			}
+			if (output.Decision == DecisionApproved) != (len(output.Violations) == 0) {
+				t.Fatalf("output %q decision %s not matching its violations", line, output.Decision)
+			}
+			if output.Decision == DecisionApproved && output.Account.AvailableLimit < 0 {
+				t.Fatalf("output %q approved with a negative limit", line)
+			}
+		}
+	})
+}
@@ -0,0 +1,237 @@
+package authorizer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// labels of the generated transactions, the ground truth of what they are
+const (
+	LabelLegit       = "legit"
+	LabelDoubled     = "doubled-transaction"
+	LabelBurst       = "burst"
+	LabelCardTesting = "card-testing"
+)
+
+var fraudPatterns = []string{LabelDoubled, LabelBurst, LabelCardTesting}
+
+// GenerateOptions configures Generate, zero values take the defaults
+type GenerateOptions struct {
+	// Seed makes the stream reproducible, the same options always generate the same stream
+	Seed         int64
+	Accounts     int // default 10
+	Transactions int // default 1000
+	Limit        int // available limit of every account, default 10000
+	Merchants    int // default 50
+	// MerchantSkew is the exponent (> 1) of the Zipf distribution of the merchants, the higher the more
+	// transactions go to the first ones, default 1.2
+	MerchantSkew float64
+	// MeanAmount and AmountSpread are the median and the sigma of the log-normal distribution of amounts,
+	// default 50 and 1
+	MeanAmount   int
+	AmountSpread float64
+	// MeanInterval is the mean of the exponential distribution of the time between transactions, default 1m
+	MeanInterval time.Duration
+	// FraudRate is the probability for a transaction to start a fraud pattern instead, 0.02 when nil
+	// (a pointer, so that zero generates no fraud)
+	FraudRate *float64
+	Start     time.Time // time of the first transaction, default 2019-02-13T10:00:00Z
+}
+
+func (options GenerateOptions) withDefaults() GenerateOptions {
+	if options.Accounts <= 0 {
+		options.Accounts = 10
+	}
+	if options.Transactions <= 0 {
+		options.Transactions = 1000
+	}
+	if options.Limit <= 0 {
+		options.Limit = 10000
+	}
+	if options.Merchants <= 0 {
+		options.Merchants = 50
+	}
+	if options.MerchantSkew <= 1 {
+		options.MerchantSkew = 1.2
+	}
+	if options.MeanAmount <= 0 {
+		options.MeanAmount = 50
+	}
+	if options.AmountSpread <= 0 {
+		options.AmountSpread = 1
+	}
+	if options.MeanInterval <= 0 {
+		options.MeanInterval = time.Minute
+	}
+	if options.FraudRate == nil {
+		rate := 0.02
+		options.FraudRate = &rate
+	}
+	if options.Start.IsZero() {
+		options.Start = time.Date(2019, 2, 13, 10, 0, 0, 0, time.UTC)
+	}
+	return options
+}
+
+// generator writes the operations and their labels as it draws them
+type generator struct {
+	options   GenerateOptions
+	random    *rand.Rand
+	merchants *rand.Zipf
+	now       time.Time
+	count     int
+	writer    *bufio.Writer
+	labels    *csv.Writer
+}
+
+// Generate writes a stream of json lines operations: the accounts first, then transactions between them.
+// The label of every transaction is written to labels as CSV (id, account-id, label) unless labels is nil.
+func Generate(writer io.Writer, labels io.Writer, options GenerateOptions) error {
+	options = options.withDefaults()
+	random := rand.New(rand.NewSource(options.Seed))
+	generator := &generator{
+		options:   options,
+		random:    random,
+		merchants: rand.NewZipf(random, options.MerchantSkew, 1, uint64(options.Merchants-1)),
+		now:       options.Start,
+		writer:    bufio.NewWriter(writer),
+	}
+	if labels != nil {
+		generator.labels = csv.NewWriter(labels)
+		if err := generator.labels.Write([]string{"id", "account-id", "label"}); err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < options.Accounts; i++ {
+		account := Account{ID: generatorAccountID(i), ActiveCard: true, AvailableLimit: options.Limit}
+		if err := generator.write(Operation{Account: &account}, ""); err != nil {
+			return err
+		}
+	}
+
+	for generator.count < options.Accounts+options.Transactions {
+		generator.now = generator.now.Add(generator.interval(options.MeanInterval))
+		account := generatorAccountID(random.Intn(options.Accounts))
+
+		var err error
+		if random.Float64() < *options.FraudRate {
+			err = generator.fraud(account, fraudPatterns[random.Intn(len(fraudPatterns))])
+		} else {
+			err = generator.transaction(account, generator.merchant(), generator.amount(), LabelLegit)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := generator.writer.Flush(); err != nil {
+		return err
+	}
+	if generator.labels != nil {
+		generator.labels.Flush()
+		return generator.labels.Error()
+	}
+	return nil
+}
+
+func generatorAccountID(i int) string {
+	return fmt.Sprintf("acc-%04d", i+1)
+}
+
+// fraud writes the transactions of a fraud pattern, a few seconds apart
+func (generator *generator) fraud(account string, pattern string) error {
+	switch pattern {
+	case LabelDoubled:
+		merchant, amount := generator.merchant(), generator.amount()
+		if err := generator.transaction(account, merchant, amount, LabelLegit); err != nil {
+			return err
+		}
+		generator.now = generator.now.Add(generator.seconds(1, 60))
+		return generator.transaction(account, merchant, amount, LabelDoubled)
+	case LabelBurst:
+		count := 4 + generator.random.Intn(3)
+		for i := 0; i < count; i++ {
+			if i > 0 {
+				generator.now = generator.now.Add(generator.seconds(1, 20))
+			}
+			if err := generator.transaction(account, generator.merchant(), generator.amount(), LabelBurst); err != nil {
+				return err
+			}
+		}
+	case LabelCardTesting:
+		// small amounts at distinct merchants
+		first, count := generator.random.Intn(generator.options.Merchants), 3+generator.random.Intn(3)
+		for i := 0; i < count; i++ {
+			if i > 0 {
+				generator.now = generator.now.Add(generator.seconds(5, 90))
+			}
+			merchant := generatorMerchant((first + i) % generator.options.Merchants)
+			if err := generator.transaction(account, merchant, 1+generator.random.Intn(5), LabelCardTesting); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// transaction writes a transaction, unless the stream already holds every transaction asked for
+func (generator *generator) transaction(account string, merchant string, amount int, label string) error {
+	if generator.count >= generator.options.Accounts+generator.options.Transactions {
+		return nil
+	}
+	return generator.write(Operation{Transaction: &Transaction{
+		AccountID: account,
+		Merchant:  merchant,
+		Amount:    amount,
+		Time:      generator.now.Format("2006-01-02T15:04:05.000Z07:00"),
+	}}, label)
+}
+
+// write writes an operation with the next id, and its label when it is a transaction
+func (generator *generator) write(operation Operation, label string) error {
+	generator.count++
+	operation.ID = strconv.Itoa(generator.count)
+	jsonData, err := json.Marshal(operation)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(generator.writer, string(jsonData)); err != nil {
+		return err
+	}
+
+	if generator.labels != nil && operation.Transaction != nil {
+		return generator.labels.Write([]string{operation.ID, operation.Transaction.AccountID, label})
+	}
+	return nil
+}
+
+func generatorMerchant(i int) string {
+	return fmt.Sprintf("Merchant %03d", i+1)
+}
+
+func (generator *generator) merchant() string {
+	return generatorMerchant(int(generator.merchants.Uint64()))
+}
+
+// amount draws from a log-normal distribution, rounded to at least 1
+func (generator *generator) amount() int {
+	value := float64(generator.options.MeanAmount) * math.Exp(generator.options.AmountSpread*generator.random.NormFloat64())
+	return int(math.Max(1, math.Round(value)))
+}
+
+// interval draws from an exponential distribution, rounded to the second
+func (generator *generator) interval(mean time.Duration) time.Duration {
+	return time.Duration(generator.random.ExpFloat64() * float64(mean)).Round(time.Second)
+}
+
+func (generator *generator) seconds(min int, max int) time.Duration {
+	return time.Duration(min+generator.random.Intn(max-min+1)) * time.Second
+}
@@ -0,0 +1,104 @@
+package authorizer
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateReproducible(t *testing.T) {
+	var first, second, other bytes.Buffer
+	_ = Generate(&first, nil, GenerateOptions{Seed: 7, Transactions: 100})
+	_ = Generate(&second, nil, GenerateOptions{Seed: 7, Transactions: 100})
+	_ = Generate(&other, nil, GenerateOptions{Seed: 8, Transactions: 100})
+
+	if first.String() == second.String() && first.String() != other.String() {
+		t.Logf("Generate(...) PASSED \nexpected: same stream for the same seed \nresult: %v", first.Len())
+	} else {
+		t.Errorf("Generate(...) FAILED \nexpected: same stream for the same seed \nresult: %v %v %v", first.Len(), second.Len(), other.Len())
+	}
+}
+
+func TestGenerateValid(t *testing.T) {
+	var writer, labels bytes.Buffer
+	err := Generate(&writer, &labels, GenerateOptions{Seed: 1, Accounts: 5, Transactions: 500, FraudRate: rate(0.2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errs bytes.Buffer
+	validation, err := Validate(strings.NewReader(writer.String()), FormatNDJSON, &errs)
+
+	expected := ValidationResult{Valid: 505}
+	if err == nil && reflect.DeepEqual(expected, validation) {
+		t.Logf("Validate(Generate(...)) PASSED \nexpected: %v \nresult: %v", expected, validation)
+	} else {
+		t.Errorf("Validate(Generate(...)) FAILED \nexpected: %v \nresult: %v %v %v", expected, validation, errs.String(), err)
+	}
+
+	rows := strings.Split(strings.TrimSpace(labels.String()), "\n")
+	counts := map[string]int{}
+	for _, row := range rows[1:] {
+		counts[row[strings.LastIndexByte(row, ',')+1:]]++
+	}
+	for _, label := range []string{LabelLegit, LabelDoubled, LabelBurst, LabelCardTesting} {
+		if counts[label] == 0 {
+			t.Errorf("Generate(...) FAILED \nexpected: transactions labelled %s \nresult: %v", label, counts)
+		}
+	}
+	if len(rows) != 501 {
+		t.Errorf("Generate(...) FAILED \nexpected: %v labels \nresult: %v", 500, len(rows)-1)
+	}
+}
+
+func rate(value float64) *float64 {
+	return &value
+}
+
+func TestGenerateNoFraud(t *testing.T) {
+	var writer, labels bytes.Buffer
+	_ = Generate(&writer, &labels, GenerateOptions{Seed: 1, Transactions: 2000, FraudRate: rate(0)})
+
+	// a zero rate is no fraud at all, not the default one
+	expected := 0
+	result := strings.Count(labels.String(), "\n") - 1 - strings.Count(labels.String(), ","+LabelLegit+"\n")
+
+	if expected == result {
+		t.Logf("Generate(...) PASSED \nexpected: %v fraud labels \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Generate(...) FAILED \nexpected: %v fraud labels \nresult: %v", expected, result)
+	}
+}
+
+func TestGenerateDoubledDetected(t *testing.T) {
+	var writer, labels bytes.Buffer
+	_ = Generate(&writer, &labels, GenerateOptions{Seed: 3, Accounts: 2, Transactions: 300, FraudRate: rate(0.1)})
+
+	var output bytes.Buffer
+	_ = New(Options{}).Run(context.Background(), strings.NewReader(writer.String()), &output)
+	outputs := strings.Split(strings.TrimSpace(output.String()), "\n")
+
+	// every transaction labelled doubled is decided as a doubled transaction
+	doubled := 0
+	for _, row := range strings.Split(strings.TrimSpace(labels.String()), "\n")[1:] {
+		fields := strings.Split(row, ",")
+		if fields[2] != LabelDoubled {
+			continue
+		}
+		doubled++
+		var line string
+		for _, value := range outputs {
+			if strings.HasSuffix(value, `"id":"`+fields[0]+`"}`) {
+				line = value
+			}
+		}
+		if !strings.Contains(line, DoubledTransaction) {
+			t.Errorf("Authorizer.Run(Generate(...)) FAILED \nexpected: %v \nresult: %v", DoubledTransaction, line)
+		}
+	}
+	if doubled == 0 {
+		t.Errorf("Generate(...) FAILED \nexpected: doubled transactions \nresult: %v", doubled)
+	}
+}
@@ -0,0 +1,330 @@
+package authorizer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+)
+
+const (
+	kvPut    byte = 'p'
+	kvDelete byte = 'd'
+
+	// crc32 + kind + key length + value length
+	kvHeaderSize = 4 + 1 + 4 + 4
+
+	// kvCompactSize is the size under which a file is never compacted
+	kvCompactSize = 1 << 20
+)
+
+// ErrKVLocked is returned when opening a file another KV has open, in this process or another one
+var ErrKVLocked = errors.New("kv: file is already open")
+
+type kvEntry struct {
+	offset int64
+	length int
+}
+
+// KV is a pure Go embedded key-value store kept in a single append-only file.
+// Only the position of every live value is kept in memory, values are read from disk on demand.
+// Once most of the file is overwritten or deleted records, the live ones are rewritten into a new file.
+type KV struct {
+	path  string
+	file  *os.File
+	size  int64
+	index map[string]kvEntry
+	keys  []string
+	// live is the size of the records of the live values
+	live int64
+	// compactSize is the size under which the file is never compacted
+	compactSize int64
+}
+
+func OpenKV(path string) (*KV, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	// each KV writes at the end of the file as it knows it, two of them would overwrite each other
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%w: %s", err, path)
+	}
+
+	kv := &KV{
+		path:        path,
+		file:        file,
+		index:       map[string]kvEntry{},
+		compactSize: kvCompactSize,
+	}
+
+	if err := kv.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := kv.compactIfGarbage(); err != nil {
+		kv.file.Close()
+		return nil, err
+	}
+
+	return kv, nil
+}
+
+// load rebuilds the index from the file, a torn record at the end (e.g. after a crash) is discarded
+// load indexes the records of the file. A torn tail, a last record running past the end of the file
+// as left by a crash while appending, is cut; a record whose checksum does not match is an error, unless
+// it is the last one.
+func (kv *KV) load() error {
+	info, err := kv.file.Stat()
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(kv.file)
+	header := make([]byte, kvHeaderSize)
+	var offset int64
+
+	for offset < info.Size() {
+		if offset+kvHeaderSize > info.Size() {
+			break
+		}
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return err
+		}
+
+		kind := header[4]
+		keyLength := int64(binary.BigEndian.Uint32(header[5:9]))
+		valueLength := int64(binary.BigEndian.Uint32(header[9:13]))
+		end := offset + kvHeaderSize + keyLength + valueLength
+		if end > info.Size() {
+			break
+		}
+
+		body := make([]byte, keyLength+valueLength)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return err
+		}
+
+		checksum := crc32.NewIEEE()
+		checksum.Write(header[4:])
+		checksum.Write(body)
+		if checksum.Sum32() != binary.BigEndian.Uint32(header[0:4]) {
+			if end == info.Size() {
+				break
+			}
+			return fmt.Errorf("kv: record at offset %d of %s is corrupted", offset, kv.path)
+		}
+
+		key := string(body[:keyLength])
+		switch kind {
+		case kvPut:
+			kv.set(key, kvEntry{offset: offset + kvHeaderSize + keyLength, length: int(valueLength)})
+		case kvDelete:
+			kv.unset(key)
+		default:
+			return fmt.Errorf("kv: unknown record kind %q at offset %d", kind, offset)
+		}
+
+		offset = end
+	}
+
+	kv.size = offset
+	if offset == info.Size() {
+		return nil
+	}
+	return kv.file.Truncate(offset)
+}
+
+func recordSize(key string, entry kvEntry) int64 {
+	return kvHeaderSize + int64(len(key)+entry.length)
+}
+
+func (kv *KV) set(key string, entry kvEntry) {
+	if previous, ok := kv.index[key]; ok {
+		kv.live -= recordSize(key, previous)
+	} else {
+		i := sort.SearchStrings(kv.keys, key)
+		kv.keys = append(kv.keys, "")
+		copy(kv.keys[i+1:], kv.keys[i:])
+		kv.keys[i] = key
+	}
+	kv.index[key] = entry
+	kv.live += recordSize(key, entry)
+}
+
+func (kv *KV) unset(key string) {
+	entry, ok := kv.index[key]
+	if !ok {
+		return
+	}
+	i := sort.SearchStrings(kv.keys, key)
+	kv.keys = append(kv.keys[:i], kv.keys[i+1:]...)
+	delete(kv.index, key)
+	kv.live -= recordSize(key, entry)
+}
+
+func encodeRecord(kind byte, key string, value []byte) []byte {
+	record := make([]byte, kvHeaderSize+len(key)+len(value))
+	record[4] = kind
+	binary.BigEndian.PutUint32(record[5:9], uint32(len(key)))
+	binary.BigEndian.PutUint32(record[9:13], uint32(len(value)))
+	copy(record[kvHeaderSize:], key)
+	copy(record[kvHeaderSize+len(key):], value)
+	binary.BigEndian.PutUint32(record[0:4], crc32.ChecksumIEEE(record[4:]))
+	return record
+}
+
+func (kv *KV) write(kind byte, key string, value []byte) (int64, error) {
+	record := encodeRecord(kind, key, value)
+
+	offset := kv.size
+	if _, err := kv.file.WriteAt(record, offset); err != nil {
+		return 0, err
+	}
+	kv.size += int64(len(record))
+
+	return offset, nil
+}
+
+func (kv *KV) Get(key string) ([]byte, bool, error) {
+	entry, ok := kv.index[key]
+	if !ok {
+		return nil, false, nil
+	}
+
+	value := make([]byte, entry.length)
+	if _, err := kv.file.ReadAt(value, entry.offset); err != nil {
+		return nil, false, err
+	}
+
+	return value, true, nil
+}
+
+func (kv *KV) Put(key string, value []byte) error {
+	offset, err := kv.write(kvPut, key, value)
+	if err != nil {
+		return err
+	}
+	kv.set(key, kvEntry{offset: offset + kvHeaderSize + int64(len(key)), length: len(value)})
+	return kv.compactIfGarbage()
+}
+
+func (kv *KV) Delete(key string) error {
+	if _, ok := kv.index[key]; !ok {
+		return nil
+	}
+	if _, err := kv.write(kvDelete, key, nil); err != nil {
+		return err
+	}
+	kv.unset(key)
+	return kv.compactIfGarbage()
+}
+
+// Size returns the size of the file, and how much of it holds live values
+func (kv *KV) Size() (int64, int64) {
+	return kv.size, kv.live
+}
+
+// compactIfGarbage compacts the file once more than half of it is overwritten or deleted records
+func (kv *KV) compactIfGarbage() error {
+	if kv.size < kv.compactSize || kv.size-kv.live <= kv.live {
+		return nil
+	}
+	return kv.Compact()
+}
+
+// Compact rewrites the live values into a new file, which then replaces the current one.
+// A crash while compacting leaves the current file as it was.
+func (kv *KV) Compact() error {
+	path := kv.path + ".compact"
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	// locked before it replaces the file, so it is never open unlocked under the path
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	writer := bufio.NewWriter(file)
+	index := make(map[string]kvEntry, len(kv.index))
+	var offset int64
+	for _, key := range kv.keys {
+		value, _, err := kv.Get(key)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		record := encodeRecord(kvPut, key, value)
+		if _, err := writer.Write(record); err != nil {
+			file.Close()
+			return err
+		}
+		index[key] = kvEntry{offset: offset + kvHeaderSize + int64(len(key)), length: len(value)}
+		offset += int64(len(record))
+	}
+
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := os.Rename(path, kv.path); err != nil {
+		file.Close()
+		return err
+	}
+
+	kv.file.Close()
+	kv.file = file
+	kv.index = index
+	kv.size = offset
+	kv.live = offset
+	return nil
+}
+
+// Keys returns every key in [start, end) in key order
+func (kv *KV) Keys(start string, end string) []string {
+	var keys []string
+	for i := sort.SearchStrings(kv.keys, start); i < len(kv.keys) && kv.keys[i] < end; i++ {
+		keys = append(keys, kv.keys[i])
+	}
+	return keys
+}
+
+// Scan calls fn for every key in [start, end) in key order
+func (kv *KV) Scan(start string, end string, fn func(key string, value []byte) error) error {
+	for _, key := range kv.Keys(start, end) {
+		value, ok, err := kv.Get(key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := fn(key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (kv *KV) Sync() error {
+	return kv.file.Sync()
+}
+
+func (kv *KV) Close() error {
+	if err := kv.file.Sync(); err != nil {
+		kv.file.Close()
+		return err
+	}
+	return kv.file.Close()
+}
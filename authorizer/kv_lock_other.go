@@ -0,0 +1,10 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package authorizer
+
+import "os"
+
+// lockFile does not lock on this system, a file must not be opened by two processes at once
+func lockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,36 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package authorizer
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestKVLocked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.kv")
+
+	kv, err := OpenKV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = kv.Put("a", []byte("1"))
+	// a compacted file is locked too
+	if err := kv.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, lockedErr := OpenKV(path)
+	_ = kv.Close()
+	reopened, err := OpenKV(path)
+	if err == nil {
+		_ = reopened.Close()
+	}
+
+	if errors.Is(lockedErr, ErrKVLocked) && err == nil {
+		t.Logf("OpenKV(...) PASSED \nexpected: %v \nresult: %v", ErrKVLocked, lockedErr)
+	} else {
+		t.Errorf("OpenKV(...) FAILED \nexpected: %v \nresult: %v %v", ErrKVLocked, lockedErr, err)
+	}
+}
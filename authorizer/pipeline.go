@@ -0,0 +1,186 @@
+package authorizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+var errPipelineStopped = errors.New("pipeline stopped")
+
+type pipelineJob struct {
+	sequence  int
+	line      int
+	record    interface{}
+	operation interface{}
+	decodeErr error
+	output    AccountOperationOutput
+	err       error
+}
+
+// shard returns the worker in charge of an account, so operations of the same account are applied in order
+func shard(id string, workers int) int {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(id))
+	return int(hash.Sum32() % uint32(workers))
+}
+
+// processParallel decodes records in parallel, applies the operations of every account in order on the
+// worker owning its shard and encodes the outputs in input order. When ctx is done it stops reading,
+// records already read are still decided and encoded.
+func (authorizer *Authorizer) processParallel(ctx context.Context, decoder Decoder, encoder Encoder) error {
+	workers := authorizer.workers
+	if workers <= 1 {
+		return authorizer.process(ctx, decoder, encoder)
+	}
+
+	// closed when an operation fails, so nothing else is read or applied
+	failed := make(chan struct{})
+	reading, stop := context.WithCancel(ctx)
+	defer stop()
+
+	records := readRecords(reading, decoder)
+	jobs := make(chan *pipelineJob, workers*64)
+	decoded := make(chan *pipelineJob, workers*64)
+	results := make(chan *pipelineJob, workers*64)
+	shards := make([]chan *pipelineJob, workers)
+	for i := range shards {
+		shards[i] = make(chan *pipelineJob, 64)
+	}
+
+	// number records, the reader may be blocked on a stream so stop waiting for it once reading is done
+	go func() {
+		defer close(jobs)
+		for sequence := 0; ; sequence++ {
+			select {
+			case record, ok := <-records:
+				if !ok {
+					return
+				}
+				jobs <- &pipelineJob{sequence: sequence, line: record.line, record: record.value, decodeErr: record.err}
+			case <-reading.Done():
+				return
+			}
+		}
+	}()
+
+	// decode records in parallel
+	var decoders sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		decoders.Add(1)
+		go func() {
+			defer decoders.Done()
+			for job := range jobs {
+				if job.decodeErr == nil {
+					job.operation, job.decodeErr = decoder.Decode(job.record)
+					job.operation = withLineID(job.operation, job.line)
+				}
+				decoded <- job
+			}
+		}()
+	}
+	go func() {
+		decoders.Wait()
+		close(decoded)
+	}()
+
+	// dispatch operations in input order to the shard of their account
+	go func() {
+		pending := map[int]*pipelineJob{}
+		next := 0
+		for job := range decoded {
+			pending[job.sequence] = job
+			for job, ok := pending[next]; ok; job, ok = pending[next] {
+				delete(pending, next)
+				next++
+
+				if job.decodeErr != nil {
+					results <- job
+					continue
+				}
+				shards[shard(accountID(job.operation), workers)] <- job
+			}
+		}
+		for i := range shards {
+			close(shards[i])
+		}
+	}()
+
+	// apply operations, one worker per shard
+	var appliers sync.WaitGroup
+	for i := range shards {
+		appliers.Add(1)
+		go func(jobs chan *pipelineJob) {
+			defer appliers.Done()
+			for job := range jobs {
+				select {
+				case <-failed:
+					job.err = errPipelineStopped
+				default:
+					job.output, job.err = apply(job.operation, authorizer.storage, authorizer.rules)
+				}
+				results <- job
+			}
+		}(shards[i])
+	}
+	go func() {
+		appliers.Wait()
+		close(results)
+	}()
+
+	// encode outputs in input order
+	var failure error
+	pending := map[int]*pipelineJob{}
+	next := 0
+
+	for job := range results {
+		pending[job.sequence] = job
+		for job, ok := pending[next]; ok; job, ok = pending[next] {
+			delete(pending, next)
+			next++
+
+			if failure != nil {
+				continue
+			}
+			if job.decodeErr != nil {
+				fmt.Fprintf(authorizer.errors, "line %d: %v\n", job.line, job.decodeErr)
+				continue
+			}
+			if errors.Is(job.err, ErrInvalidOperation) {
+				fmt.Fprintf(authorizer.errors, "line %d: %v\n", job.line, job.err)
+				continue
+			}
+
+			err := job.err
+			if err == nil && authorizer.audit != nil {
+				var input string
+				if input, err = auditInput(job.record, job.operation); err == nil {
+					err = authorizer.audit.Append(input, job.output)
+				}
+			}
+			if err == nil {
+				err = encoder.Encode(job.output)
+			}
+			if err != nil {
+				failure = err
+				close(failed)
+				stop()
+			}
+		}
+
+		if failure == nil && len(pending) == 0 && len(results) == 0 {
+			if err := encoder.Flush(); err != nil {
+				failure = err
+				close(failed)
+				stop()
+			}
+		}
+	}
+
+	if failure != nil {
+		return failure
+	}
+	return ctx.Err()
+}
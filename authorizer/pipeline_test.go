@@ -0,0 +1,74 @@
+package authorizer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testOperations builds an input where the transactions of several accounts are interleaved
+func testOperations(accounts int, transactions int) string {
+	var lines []string
+	start := time.Date(2019, 2, 13, 10, 0, 0, 0, time.UTC)
+	merchants := []string{"Burger King", "Habbib's", "McDonald's", "Subway"}
+
+	for i := 0; i < accounts; i++ {
+		lines = append(lines, fmt.Sprintf(`{"account": {"account-id": "%d", "active-card": true, "available-limit": %d}}`, i, 100+i*1000))
+	}
+	for j := 0; j < transactions; j++ {
+		for i := 0; i < accounts; i++ {
+			lines = append(lines, fmt.Sprintf(`{"transaction": {"account-id": "%d", "merchant": "%s", "amount": %d, "time": "%s"}}`,
+				i, merchants[(i+j)%len(merchants)], 1+(i*j)%40, start.Add(time.Duration(j*17+i)*time.Second).Format(time.RFC3339)))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestProcessParallel(t *testing.T) {
+	input := testOperations(16, 50) + "\nnot json\n" +
+		`{"transaction": {"account-id": "unknown", "merchant": "Subway", "amount": 10, "time": "2019-02-13T10:00:00.000Z"}}`
+
+	var sequential, parallel bytes.Buffer
+	_ = New(Options{Workers: 1}).Run(context.Background(), strings.NewReader(input), &sequential)
+	err := New(Options{Workers: 4}).Run(context.Background(), strings.NewReader(input), &parallel)
+
+	expected := sequential.String()
+	result := parallel.String()
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("processParallel(...) PASSED \nexpected: %v bytes \nresult: %v bytes", len(expected), len(result))
+	} else {
+		t.Errorf("processParallel(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestShard(t *testing.T) {
+	expected := shard("42", 8)
+	result := shard("42", 8)
+
+	if reflect.DeepEqual(expected, result) && result >= 0 && result < 8 {
+		t.Logf("shard(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("shard(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func benchmarkProcessParallel(b *testing.B, workers int) {
+	input := testOperations(64, 100)
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_ = New(Options{Workers: workers}).Run(context.Background(), strings.NewReader(input), io.Discard)
+	}
+}
+
+func BenchmarkProcessParallel1(b *testing.B) { benchmarkProcessParallel(b, 1) }
+func BenchmarkProcessParallel2(b *testing.B) { benchmarkProcessParallel(b, 2) }
+func BenchmarkProcessParallel4(b *testing.B) { benchmarkProcessParallel(b, 4) }
+func BenchmarkProcessParallel8(b *testing.B) { benchmarkProcessParallel(b, 8) }
@@ -0,0 +1,109 @@
+package authorizer
+
+import (
+	"fmt"
+	"sort"
+)
+
+// severities of a violation, from the strongest
+const (
+	// SeverityHardDecline declines the transaction
+	SeverityHardDecline = "hard-decline"
+	// SeveritySoftDecline sends the transaction to review, its amount being held until it is approved or rejected
+	SeveritySoftDecline = "soft-decline"
+	// SeverityWarnOnly reports the violation, the transaction being approved if nothing else declines it
+	SeverityWarnOnly = "warn-only"
+)
+
+// decisions on an operation
+const (
+	DecisionApproved = "approved"
+	DecisionDeclined = "declined"
+	DecisionReview   = "review"
+)
+
+// specificationViolations guard the account itself, they are always a hard decline
+var specificationViolations = []string{AccountNotInitialized, AccountAlreadyInitialized, CardNotActive, InsufficientLimit}
+
+// ruleViolations are the violations raised by the rules, whose severity can be configured
+var ruleViolations = []string{DoubledTransaction, HighFrequencySmallInterval, CardTestingSuspected,
+	StructuringSuspected, AnomalousTransaction, HighFraudScore}
+
+// Policy decides what the violations of an operation lead to, and in which order they are reported.
+// A violation without a severity is a hard decline, the ones left out of Order are reported after
+// the listed ones, in the order they were found.
+type Policy struct {
+	Severities map[string]string `json:"severities"`
+	Order      []string          `json:"order"`
+}
+
+func (policy Policy) severity(violation string) string {
+	if severity, ok := policy.Severities[violation]; ok {
+		return severity
+	}
+	return SeverityHardDecline
+}
+
+// decide returns the decision on an operation with the given violations
+func (policy Policy) decide(violations []string) string {
+	decision := DecisionApproved
+	for _, violation := range violations {
+		switch policy.severity(violation) {
+		case SeverityHardDecline:
+			return DecisionDeclined
+		case SeveritySoftDecline:
+			decision = DecisionReview
+		}
+	}
+	return decision
+}
+
+// sort orders the violations in place as configured
+func (policy Policy) sort(violations []string) {
+	if len(policy.Order) == 0 {
+		return
+	}
+	rank := func(violation string) int {
+		for i, ordered := range policy.Order {
+			if ordered == violation {
+				return i
+			}
+		}
+		return len(policy.Order)
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		return rank(violations[i]) < rank(violations[j])
+	})
+}
+
+func (policy Policy) validate() error {
+	for violation, severity := range policy.Severities {
+		switch severity {
+		case SeverityHardDecline, SeveritySoftDecline, SeverityWarnOnly:
+		default:
+			return fmt.Errorf("severity %q of %s not valid", severity, violation)
+		}
+		if contains(specificationViolations, violation) {
+			if severity != SeverityHardDecline {
+				return fmt.Errorf("%s is always a %s", violation, SeverityHardDecline)
+			}
+		} else if !contains(ruleViolations, violation) {
+			return fmt.Errorf("violation %q not known", violation)
+		}
+	}
+	for _, violation := range policy.Order {
+		if !contains(specificationViolations, violation) && !contains(ruleViolations, violation) {
+			return fmt.Errorf("violation %q not known", violation)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package authorizer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPolicyDecide(t *testing.T) {
+	policy := Policy{Severities: map[string]string{
+		AnomalousTransaction: SeverityWarnOnly,
+		HighFraudScore:       SeveritySoftDecline,
+	}}
+
+	expected := []string{DecisionApproved, DecisionApproved, DecisionReview, DecisionDeclined, DecisionDeclined}
+	result := []string{
+		policy.decide(nil),
+		policy.decide([]string{AnomalousTransaction}),
+		policy.decide([]string{AnomalousTransaction, HighFraudScore}),
+		policy.decide([]string{HighFraudScore, DoubledTransaction}),
+		Policy{}.decide([]string{AnomalousTransaction}),
+	}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("Policy.decide(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Policy.decide(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestPolicySort(t *testing.T) {
+	policy := Policy{Order: []string{HighFraudScore, DoubledTransaction}}
+	result := []string{InsufficientLimit, DoubledTransaction, HighFrequencySmallInterval, HighFraudScore}
+	policy.sort(result)
+
+	// the violations left out of the order keep theirs
+	expected := []string{HighFraudScore, DoubledTransaction, InsufficientLimit, HighFrequencySmallInterval}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("Policy.sort(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Policy.sort(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestLoadRulesPolicyNotValid(t *testing.T) {
+	for _, data := range []string{
+		`{"policy": {"severities": {"doubled-transaction": "ignore"}}}`,
+		`{"policy": {"severities": {"insufficient-limit": "warn-only"}}}`,
+		`{"policy": {"severities": {"unknown-violation": "warn-only"}}}`,
+		`{"policy": {"order": ["unknown-violation"]}}`,
+	} {
+		path := filepath.Join(t.TempDir(), "rules.json")
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err := LoadRules(path)
+
+		if err != nil {
+			t.Logf("LoadRules(%s) PASSED \nexpected: error \nresult: %v", data, err)
+		} else {
+			t.Errorf("LoadRules(%s) FAILED \nexpected: error \nresult: %v", data, err)
+		}
+	}
+}
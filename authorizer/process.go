@@ -0,0 +1,509 @@
+package authorizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+type Account struct {
+	ID             string `json:"account-id,omitempty"`
+	ActiveCard     bool   `json:"active-card"`
+	AvailableLimit int    `json:"available-limit"`
+}
+
+type Transaction struct {
+	AccountID string      `json:"account-id,omitempty"`
+	Merchant  string      `json:"merchant"`
+	Amount    int         `json:"amount"`
+	Time      interface{} `json:"time"`
+	MCC       string      `json:"mcc,omitempty"` // merchant category code
+	// declined is set on the transactions of the history that were declined
+	declined bool
+}
+
+type AccountOperation struct {
+	ID      string `json:"id,omitempty"`
+	Account Account
+}
+
+type TransactionOperation struct {
+	ID          string `json:"id,omitempty"`
+	Transaction Transaction
+	// lineID is set when ID is the line of the operation in its input rather than an id it came with
+	lineID bool
+}
+
+type AccountOperationOutput struct {
+	Account    Account  `json:"account"`
+	Violations []string `json:"violations"`
+	// Decision the policy derives from the violations
+	Decision string `json:"decision"`
+	// ReviewID identifies the queued review when the decision is review
+	ReviewID string `json:"review-id,omitempty"`
+	// AnomalyScore is the score of a transaction against the profile of its account, zero when not scored
+	AnomalyScore float64 `json:"anomaly-score,omitempty"`
+	// FraudScore is the probability of fraud given by the fraud score model, zero when not scored
+	FraudScore float64 `json:"fraud-score,omitempty"`
+	ID         string  `json:"id,omitempty"` // id of the operation, its line when the input has none
+}
+
+type AccountStatus struct {
+	account    Account
+	hasAccount bool
+	// account as it was first initialized, used as pivot by some rules
+	initial Account
+	// statistics of the approved transactions
+	profile Profile
+	// amount of the transactions waiting for review, already taken from the available limit
+	held int
+	// times the account was initialized again, each one replacing the limit and dropping what was held
+	reinitializations int
+}
+
+const (
+	AccountNotInitialized      = "account-not-initialized"
+	AccountAlreadyInitialized  = "account-already-initialized"
+	CardNotActive              = "card-not-active"
+	DoubledTransaction         = "doubled-transaction"
+	InsufficientLimit          = "insufficient-limit"
+	HighFrequencySmallInterval = "high-frequency-small-interval"
+)
+
+func processAccount(operation AccountOperation, accountStatus AccountStatus, rules Rules) AccountOperationOutput {
+	var violations []string
+	var activeCard bool
+	var availableLimit int
+
+	if accountStatus.hasAccount && accountStatus.account.ActiveCard == operation.Account.ActiveCard {
+		violations = []string{AccountAlreadyInitialized}
+		activeCard = accountStatus.account.ActiveCard
+		availableLimit = accountStatus.account.AvailableLimit
+	} else {
+		activeCard = operation.Account.ActiveCard
+		availableLimit = operation.Account.AvailableLimit
+	}
+
+	return AccountOperationOutput{Account: Account{
+		ID:             operation.Account.ID,
+		ActiveCard:     activeCard,
+		AvailableLimit: availableLimit,
+	}, Violations: violations, Decision: rules.Policy.decide(violations)}
+}
+
+func processTransaction(new TransactionOperation, status AccountStatus, storage Storage, rules Rules) (AccountOperationOutput, error) {
+	var violations []string
+	var score, fraud float64
+	var account Account
+	decision := DecisionDeclined
+	id := accountID(new)
+
+	account.ID = new.Transaction.AccountID
+
+	if !status.hasAccount {
+		account.ActiveCard = false
+		account.AvailableLimit = 0
+		violations = []string{AccountNotInitialized}
+	} else {
+		account.ActiveCard = status.account.ActiveCard
+		account.AvailableLimit = status.account.AvailableLimit - new.Transaction.Amount
+
+		if account.AvailableLimit < 0 {
+			violations = []string{InsufficientLimit}
+		}
+
+		if !status.account.ActiveCard {
+			violations = append(violations, CardNotActive)
+		}
+
+		doubled, err := hasDoubledTransaction(storage, id, new.Transaction)
+		if err != nil {
+			return AccountOperationOutput{}, err
+		}
+		if doubled {
+			violations = append(violations, DoubledTransaction)
+		}
+
+		highFrequency, err := hasHighFrequencySmallInterval(status.initial, storage, id, new.Transaction)
+		if err != nil {
+			return AccountOperationOutput{}, err
+		}
+		if highFrequency {
+			violations = append(violations, HighFrequencySmallInterval)
+		}
+
+		cardTesting, err := hasCardTesting(rules.CardTesting, storage, id, new.Transaction)
+		if err != nil {
+			return AccountOperationOutput{}, err
+		}
+		if cardTesting {
+			violations = append(violations, CardTestingSuspected)
+		}
+
+		structuring, err := hasStructuring(rules.Structuring, storage, id, new.Transaction)
+		if err != nil {
+			return AccountOperationOutput{}, err
+		}
+		if structuring {
+			violations = append(violations, StructuringSuspected)
+		}
+
+		var scored bool
+		score, scored = anomalyScore(rules.Anomaly, status.profile, new.Transaction)
+		if scored && score >= rules.Anomaly.Threshold {
+			violations = append(violations, AnomalousTransaction)
+		}
+
+		fraud, scored, err = fraudScore(rules.FraudScore, status, storage, id, new.Transaction)
+		if err != nil {
+			return AccountOperationOutput{}, err
+		}
+		if scored && fraud >= rules.FraudScore.Threshold {
+			violations = append(violations, HighFraudScore)
+		}
+
+		rules.Policy.sort(violations)
+		decision = rules.Policy.decide(violations)
+		// a transaction sent to review holds its amount
+		if decision == DecisionDeclined {
+			account.AvailableLimit = status.account.AvailableLimit
+		}
+	}
+
+	return AccountOperationOutput{Account: account, Violations: violations, Decision: decision,
+		AnomalyScore: score, FraudScore: fraud}, nil
+}
+
+type record struct {
+	value interface{}
+	err   error
+	line  int
+}
+
+// readRecords sends every record read by the decoder until the input is exhausted or ctx is done
+func readRecords(ctx context.Context, decoder Decoder) <-chan record {
+	records := make(chan record, 64)
+
+	go func() {
+		defer close(records)
+		for ordinal := 1; ; ordinal++ {
+			value, err := decoder.Read()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			select {
+			case records <- record{value, err, recordLine(decoder, ordinal)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return records
+}
+
+// process applies every record in order and encodes their outputs, which are flushed whenever no more
+// records are waiting. It stops reading when ctx is done, returning its error.
+func (authorizer *Authorizer) process(ctx context.Context, decoder Decoder, encoder Encoder) error {
+	records := readRecords(ctx, decoder)
+
+	for {
+		var record record
+		var ok bool
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case record, ok = <-records:
+			if !ok {
+				return ctx.Err()
+			}
+		}
+
+		if record.err != nil {
+			fmt.Fprintf(authorizer.errors, "line %d: %v\n", record.line, record.err)
+			continue
+		}
+
+		operation, err := decoder.Decode(record.value)
+		if err != nil {
+			fmt.Fprintf(authorizer.errors, "line %d: %v\n", record.line, err)
+			continue
+		}
+		operation = withLineID(operation, record.line)
+
+		output, err := apply(operation, authorizer.storage, authorizer.rules)
+		if errors.Is(err, ErrInvalidOperation) {
+			fmt.Fprintf(authorizer.errors, "line %d: %v\n", record.line, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if authorizer.audit != nil {
+			input, err := auditInput(record.value, operation)
+			if err != nil {
+				return err
+			}
+			if err := authorizer.audit.Append(input, output); err != nil {
+				return err
+			}
+		}
+
+		if err := encoder.Encode(output); err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			if err := encoder.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// operationID returns the id of an operation, empty when it has none
+func operationID(operation interface{}) string {
+	switch operation := operation.(type) {
+	case AccountOperation:
+		return operation.ID
+	case TransactionOperation:
+		return operation.ID
+	}
+	return ""
+}
+
+// withLineID sets the id of an operation without one to its line in the input
+func withLineID(operation interface{}, line int) interface{} {
+	if operationID(operation) != "" {
+		return operation
+	}
+
+	id := strconv.Itoa(line)
+	switch value := operation.(type) {
+	case AccountOperation:
+		value.ID = id
+		return value
+	case TransactionOperation:
+		value.ID = id
+		value.lineID = true
+		return value
+	}
+	return operation
+}
+
+// accountID returns the id of the account an operation belongs to
+func accountID(operation interface{}) string {
+	var id string
+	switch operation := operation.(type) {
+	case AccountOperation:
+		id = operation.Account.ID
+	case TransactionOperation:
+		id = operation.Transaction.AccountID
+	}
+
+	if id == "" {
+		return defaultAccountID
+	}
+	return id
+}
+
+// decode returns the AccountOperation or TransactionOperation described by a json line,
+// its time parsed in location
+func decode(line string, location *time.Location) (interface{}, error) {
+	operation, err := decodeStrict(line)
+	if err != nil {
+		return nil, err
+	}
+	return fromOperation(operation, location)
+}
+
+// apply processes a decoded operation against the account state kept in storage
+func apply(operation interface{}, storage Storage, rules Rules) (AccountOperationOutput, error) {
+	output, err := applyOperation(operation, storage, rules)
+	output.ID = operationID(operation)
+	return output, err
+}
+
+func applyOperation(operation interface{}, storage Storage, rules Rules) (AccountOperationOutput, error) {
+	id := accountID(operation)
+	accountStatus, err := storage.GetAccount(id)
+	if err != nil {
+		return AccountOperationOutput{}, err
+	}
+
+	switch operation := operation.(type) {
+	case AccountOperation:
+		output := processAccount(operation, accountStatus, rules)
+
+		if output.Decision == DecisionApproved {
+			if !accountStatus.hasAccount {
+				accountStatus.initial = output.Account
+			} else {
+				// the new limit does not account for the transactions waiting for review
+				accountStatus.reinitializations++
+				accountStatus.held = 0
+			}
+			accountStatus.hasAccount = true
+			accountStatus.account = output.Account
+			if err := storage.PutAccount(id, accountStatus); err != nil {
+				return output, err
+			}
+		}
+
+		return output, nil
+	case TransactionOperation:
+		// get output
+		output, err := processTransaction(operation, accountStatus, storage, rules)
+		if err != nil {
+			return output, err
+		}
+
+		switch output.Decision {
+		case DecisionApproved:
+			accountStatus.account.AvailableLimit = output.Account.AvailableLimit
+			accountStatus.profile = accountStatus.profile.add(operation.Transaction, rules.Anomaly.Window)
+			if err := storage.PutAccount(id, accountStatus); err != nil {
+				return output, err
+			}
+		case DecisionReview:
+			reviewID, err := queueReview(operation, output, accountStatus, storage)
+			if err != nil {
+				return output, err
+			}
+			output.ReviewID = reviewID
+		}
+
+		operation.Transaction.declined = output.Decision == DecisionDeclined
+		if err := storage.AppendHistory(id, operation.Transaction); err != nil {
+			return output, err
+		}
+
+		return output, nil
+	default:
+		return AccountOperationOutput{}, errors.New("operation not valid")
+	}
+}
+
+type StateOptions struct {
+	InputFormat string // ndjson (default), csv or protobuf
+	// Rules the operations were decided with, nil means DefaultRules
+	Rules *Rules
+	// Location transaction times are parsed in, Etc/GMT when nil
+	Location *time.Location
+}
+
+// StateAt rebuilds the status of an account by replaying the operations in the order they were processed,
+// skipping the transactions later than the given moment, so the log does not need to be sorted by time.
+// Operations that are not valid are skipped, as they were when processed.
+func StateAt(reader io.Reader, id string, at time.Time, options StateOptions) (AccountStatus, error) {
+	decoder, err := newDecoder(options.InputFormat, reader, options.Location)
+	if err != nil {
+		return AccountStatus{}, err
+	}
+	rules := DefaultRules()
+	if options.Rules != nil {
+		rules = *options.Rules
+	}
+	storage := NewRetentionStorage(NewMemoryStorage(), defaultRetention(rules), 0)
+
+	for ordinal := 1; ; ordinal++ {
+		value, err := decoder.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			continue
+		}
+		operation, err := decoder.Decode(value)
+		if err != nil {
+			continue
+		}
+		operation = withLineID(operation, recordLine(decoder, ordinal))
+
+		if transactionOperation, ok := operation.(TransactionOperation); ok {
+			if transactionOperation.Transaction.Time.(time.Time).After(at) {
+				continue
+			}
+		}
+
+		if _, err := apply(operation, storage, rules); err != nil {
+			return AccountStatus{}, err
+		}
+	}
+
+	if id == "" {
+		id = defaultAccountID
+	}
+	return storage.GetAccount(id)
+}
+
+// ParseTime parses an RFC3339 time as used by transactions, in Etc/GMT
+func ParseTime(data string) (time.Time, error) {
+	return parseTime(data, nil)
+}
+
+// parseTime parses an RFC3339 time in a location, Etc/GMT when it is nil
+func parseTime(data string, location *time.Location) (time.Time, error) {
+	return time.ParseInLocation(time.RFC3339, data, parseLocation(location))
+}
+
+// windows in which rules look back into the history of an account
+const (
+	doubledTransactionWindow = 2 * time.Minute
+	highFrequencyWindow      = 2 * time.Minute
+)
+
+func ruleWindows() []time.Duration {
+	return []time.Duration{doubledTransactionWindow, highFrequencyWindow}
+}
+
+// recentHistory returns the transactions within a window before a transaction
+func recentHistory(storage Storage, id string, transaction Transaction, window time.Duration) ([]Transaction, error) {
+	t1 := transaction.Time.(time.Time)
+	return storage.QueryHistory(id, t1.Add(-window), time.Time{})
+}
+
+// merchantHistory returns the transactions at the merchant of a transaction within a window before it
+func merchantHistory(storage Storage, id string, transaction Transaction, window time.Duration) ([]Transaction, error) {
+	history, err := recentHistory(storage, id, transaction, window)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Transaction
+	for _, value := range history {
+		if value.Merchant == transaction.Merchant {
+			matches = append(matches, value)
+		}
+	}
+	return matches, nil
+}
+
+func hasDoubledTransaction(storage Storage, id string, transaction Transaction) (bool, error) {
+	history, err := merchantHistory(storage, id, transaction, doubledTransactionWindow)
+	if err != nil {
+		return false, err
+	}
+
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].Amount == transaction.Amount {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func hasHighFrequencySmallInterval(pivot Account, storage Storage, id string, transaction Transaction) (bool, error) {
+	if pivot.ActiveCard && pivot.AvailableLimit == 100 {
+		history, err := recentHistory(storage, id, transaction, highFrequencyWindow)
+		if err != nil {
+			return false, err
+		}
+		return len(history) >= 3, nil
+	}
+	return false, nil
+}
@@ -1,22 +1,52 @@
-package main
+package authorizer
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
 
+// newTestStorage loads the transactions of a slice of operations as the history of the default account
+func newTestStorage(operations []interface{}) Storage {
+	storage := NewMemoryStorage()
+	for _, operation := range operations {
+		if transactionOperation, ok := operation.(TransactionOperation); ok {
+			transaction := transactionOperation.Transaction
+			if data, ok := transaction.Time.(string); ok {
+				transaction.Time, _ = parseTime(data, nil)
+			}
+			_ = storage.AppendHistory(defaultAccountID, transaction)
+		}
+	}
+	return storage
+}
+
+func encodeOutput(slice []AccountOperationOutput) string {
+	var buffer bytes.Buffer
+	encoder, _ := NewEncoder(FormatNDJSON, &buffer)
+	for _, item := range slice {
+		_ = encoder.Encode(item)
+	}
+	_ = encoder.Flush()
+	return buffer.String()
+}
+
 func TestEmptyOutput(t *testing.T) {
 	expected := ""
-	result := output(nil)
+	result := encodeOutput(nil)
 	expectedOut, _ := json.Marshal(expected)
 	resultOut, _ := json.Marshal(result)
 
 	if reflect.DeepEqual(expected, result) {
-		t.Logf("output(nil) PASSED \nexpected: %v \nresult: %v", string(expectedOut), string(resultOut))
+		t.Logf("Encode(nil) PASSED \nexpected: %v \nresult: %v", string(expectedOut), string(resultOut))
 	} else {
-		t.Errorf("output(nil) FAILED \nexpected: %v \nresult: %v", string(expectedOut), string(resultOut))
+		t.Errorf("Encode(nil) FAILED \nexpected: %v \nresult: %v", string(expectedOut), string(resultOut))
 	}
 }
 
@@ -25,21 +55,21 @@ func TestNotEmptyOutput(t *testing.T) {
 		{Account: Account{
 			ActiveCard:     true,
 			AvailableLimit: 100,
-		}, Violations: nil},
+		}, Violations: nil, Decision: DecisionApproved},
 		{Account: Account{
 			ActiveCard:     true,
 			AvailableLimit: 50,
-		}, Violations: nil},
+		}, Violations: nil, Decision: DecisionApproved},
 	}
-	expected := `{"account":{"active-card":true,"available-limit":100},"violations":[]}` + "\n" + `{"account":{"active-card":true,"available-limit":50},"violations":[]}`
-	result := output(in)
+	expected := `{"account":{"active-card":true,"available-limit":100},"violations":[],"decision":"approved"}` + "\n" + `{"account":{"active-card":true,"available-limit":50},"violations":[],"decision":"approved"}` + "\n"
+	result := encodeOutput(in)
 	expectedOut, _ := json.Marshal(expected)
 	resultOut, _ := json.Marshal(result)
 
 	if reflect.DeepEqual(expected, result) {
-		t.Logf("output(...) PASSED \nexpected: %v \nresult: %v", string(expectedOut), string(resultOut))
+		t.Logf("Encode(...) PASSED \nexpected: %v \nresult: %v", string(expectedOut), string(resultOut))
 	} else {
-		t.Errorf("output(...) FAILED \nexpected: %v \nresult: %v", string(expectedOut), string(resultOut))
+		t.Errorf("Encode(...) FAILED \nexpected: %v \nresult: %v", string(expectedOut), string(resultOut))
 	}
 }
 
@@ -48,7 +78,7 @@ func TestProcessAccountWithoutViolations(t *testing.T) {
 		ActiveCard:     true,
 		AvailableLimit: 100,
 	}
-	operation := AccountOperation{account}
+	operation := AccountOperation{Account: account}
 	accountStatus := AccountStatus{
 		account:    Account{},
 		hasAccount: false,
@@ -56,8 +86,9 @@ func TestProcessAccountWithoutViolations(t *testing.T) {
 	expected := AccountOperationOutput{
 		Account:    account,
 		Violations: nil,
+		Decision:   DecisionApproved,
 	}
-	result := processAccount(operation, accountStatus)
+	result := processAccount(operation, accountStatus, DefaultRules())
 	expectedOut, _ := json.Marshal(expected)
 	resultOut, _ := json.Marshal(result)
 
@@ -73,7 +104,7 @@ func TestProcessAccountWithViolationAccountAlreadyInitialized(t *testing.T) {
 		ActiveCard:     true,
 		AvailableLimit: 100,
 	}
-	operation := AccountOperation{account}
+	operation := AccountOperation{Account: account}
 	accountStatus := AccountStatus{
 		account:    account,
 		hasAccount: true,
@@ -81,8 +112,9 @@ func TestProcessAccountWithViolationAccountAlreadyInitialized(t *testing.T) {
 	expected := AccountOperationOutput{
 		Account:    account,
 		Violations: []string{AccountAlreadyInitialized},
+		Decision:   DecisionDeclined,
 	}
-	result := processAccount(operation, accountStatus)
+	result := processAccount(operation, accountStatus, DefaultRules())
 	expectedOut, _ := json.Marshal(expected)
 	resultOut, _ := json.Marshal(result)
 
@@ -103,19 +135,24 @@ func TestProcessTransactionWithoutViolations(t *testing.T) {
 	status := AccountStatus{
 		account:    Account{ActiveCard: true, AvailableLimit: 35},
 		hasAccount: true,
+		initial:    Account{ActiveCard: false, AvailableLimit: 100},
 	}
 
-	newOperation := TransactionOperation{Transaction{
+	loc, _ := time.LoadLocation("Etc/GMT")
+	t1, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T13:01:00.000Z", loc)
+
+	newOperation := TransactionOperation{Transaction: Transaction{
 		Merchant: "Test",
 		Amount:   10,
-		Time:     "2019-02-13T13:01:00.000Z",
+		Time:     t1,
 	}}
 
 	expected := AccountOperationOutput{
 		Account:    Account{ActiveCard: true, AvailableLimit: 25},
 		Violations: nil,
+		Decision:   DecisionApproved,
 	}
-	result := processTransaction(newOperation, status, operations)
+	result, _ := processTransaction(newOperation, status, newTestStorage(operations), DefaultRules())
 
 	if reflect.DeepEqual(expected, result) {
 		t.Logf("processTransaction(...) PASSED \nexpected: %v \nresult: %v", expected, result)
@@ -132,7 +169,7 @@ func TestProcessTransactionWithViolationAccountNotInitialized(t *testing.T) {
 		hasAccount: false,
 	}
 
-	newOperation := TransactionOperation{Transaction{
+	newOperation := TransactionOperation{Transaction: Transaction{
 		Merchant: "Test",
 		Amount:   10,
 		Time:     "2019-02-13T13:01:00.000Z",
@@ -141,8 +178,9 @@ func TestProcessTransactionWithViolationAccountNotInitialized(t *testing.T) {
 	expected := AccountOperationOutput{
 		Account:    Account{ActiveCard: false, AvailableLimit: 0},
 		Violations: []string{AccountNotInitialized},
+		Decision:   DecisionDeclined,
 	}
-	result := processTransaction(newOperation, status, operations)
+	result, _ := processTransaction(newOperation, status, newTestStorage(operations), DefaultRules())
 
 	if reflect.DeepEqual(expected, result) {
 		t.Logf("processTransaction(...) PASSED \nexpected: %v \nresult: %v", expected, result)
@@ -159,12 +197,13 @@ func TestProcessTransactionWithViolationInsufficientLimit(t *testing.T) {
 	status := AccountStatus{
 		account:    Account{ActiveCard: true, AvailableLimit: 100},
 		hasAccount: true,
+		initial:    Account{ActiveCard: true, AvailableLimit: 100},
 	}
 
 	loc, _ := time.LoadLocation("Etc/GMT")
 	t1, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T13:00:00.000Z", loc)
 
-	newOperation := TransactionOperation{Transaction{
+	newOperation := TransactionOperation{Transaction: Transaction{
 		Merchant: "Test",
 		Amount:   130,
 		Time:     t1,
@@ -173,8 +212,9 @@ func TestProcessTransactionWithViolationInsufficientLimit(t *testing.T) {
 	expected := AccountOperationOutput{
 		Account:    Account{ActiveCard: true, AvailableLimit: 100},
 		Violations: []string{InsufficientLimit},
+		Decision:   DecisionDeclined,
 	}
-	result := processTransaction(newOperation, status, operations)
+	result, _ := processTransaction(newOperation, status, newTestStorage(operations), DefaultRules())
 
 	if reflect.DeepEqual(expected, result) {
 		t.Logf("processTransaction(...) PASSED \nexpected: %v \nresult: %v", expected, result)
@@ -187,22 +227,27 @@ func TestProcessTransactionWithViolationCardNotActive(t *testing.T) {
 	var operations []interface{}
 	operations = append(operations, AccountOperation{Account: Account{ActiveCard: false, AvailableLimit: 100}})
 
-	newOperation := TransactionOperation{Transaction{
+	loc, _ := time.LoadLocation("Etc/GMT")
+	t1, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T13:00:00.000Z", loc)
+
+	newOperation := TransactionOperation{Transaction: Transaction{
 		Merchant: "Test",
 		Amount:   35,
-		Time:     "2019-02-13T13:00:00.000Z",
+		Time:     t1,
 	}}
 
 	status := AccountStatus{
 		account:    Account{ActiveCard: false, AvailableLimit: 100},
 		hasAccount: true,
+		initial:    Account{ActiveCard: false, AvailableLimit: 100},
 	}
 
 	expected := AccountOperationOutput{
 		Account:    Account{ActiveCard: false, AvailableLimit: 100},
 		Violations: []string{CardNotActive},
+		Decision:   DecisionDeclined,
 	}
-	result := processTransaction(newOperation, status, operations)
+	result, _ := processTransaction(newOperation, status, newTestStorage(operations), DefaultRules())
 
 	if reflect.DeepEqual(expected, result) {
 		t.Logf("processTransaction(...) PASSED \nexpected: %v \nresult: %v", expected, result)
@@ -220,7 +265,7 @@ func TestProcessTransactionWithViolationDoubledTransaction(t *testing.T) {
 	t2, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T12:00:10.000Z", loc)
 	operations = append(operations, TransactionOperation{Transaction: Transaction{Merchant: "McDonald's", Amount: 20, Time: t2}})
 	t3, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T12:01:00.000Z", loc)
-	newOperation := TransactionOperation{Transaction{
+	newOperation := TransactionOperation{Transaction: Transaction{
 		Merchant: "McDonald's",
 		Amount:   10,
 		Time:     t3,
@@ -229,13 +274,15 @@ func TestProcessTransactionWithViolationDoubledTransaction(t *testing.T) {
 	status := AccountStatus{
 		account:    Account{ActiveCard: true, AvailableLimit: 100},
 		hasAccount: true,
+		initial:    Account{ActiveCard: true, AvailableLimit: 100},
 	}
 
 	expected := AccountOperationOutput{
 		Account:    Account{ActiveCard: true, AvailableLimit: 100},
 		Violations: []string{DoubledTransaction},
+		Decision:   DecisionDeclined,
 	}
-	result := processTransaction(newOperation, status, operations)
+	result, _ := processTransaction(newOperation, status, newTestStorage(operations), DefaultRules())
 
 	if reflect.DeepEqual(expected, result) {
 		t.Logf("processTransaction(...) PASSED \nexpected: %v \nresult: %v", expected, result)
@@ -255,7 +302,7 @@ func TestProcessTransactionWithViolationHighFrequencySmallInterval(t *testing.T)
 	t3, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T11:01:01.000Z", loc)
 	operations = append(operations, TransactionOperation{Transaction: Transaction{Merchant: "McDonald's", Amount: 20, Time: t3}})
 	t4, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T11:01:31.000Z", loc)
-	newOperation := TransactionOperation{Transaction{
+	newOperation := TransactionOperation{Transaction: Transaction{
 		Merchant: "Subway",
 		Amount:   20,
 		Time:     t4,
@@ -264,13 +311,15 @@ func TestProcessTransactionWithViolationHighFrequencySmallInterval(t *testing.T)
 	status := AccountStatus{
 		account:    Account{ActiveCard: true, AvailableLimit: 40},
 		hasAccount: true,
+		initial:    Account{ActiveCard: true, AvailableLimit: 100},
 	}
 
 	expected := AccountOperationOutput{
 		Account:    Account{ActiveCard: true, AvailableLimit: 40},
 		Violations: []string{HighFrequencySmallInterval},
+		Decision:   DecisionDeclined,
 	}
-	result := processTransaction(newOperation, status, operations)
+	result, _ := processTransaction(newOperation, status, newTestStorage(operations), DefaultRules())
 
 	if reflect.DeepEqual(expected, result) {
 		t.Logf("processTransaction(...) PASSED \nexpected: %v \nresult: %v", expected, result)
@@ -290,7 +339,7 @@ func TestProcessTransactionWithMultipleViolations_1(t *testing.T) {
 	t3, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T11:01:01.000Z", loc)
 	operations = append(operations, TransactionOperation{Transaction: Transaction{Merchant: "Burger King", Amount: 5, Time: t3}})
 	t4, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T11:00:08.000Z", loc)
-	newOperation := TransactionOperation{Transaction{
+	newOperation := TransactionOperation{Transaction: Transaction{
 		Merchant: "Burger King",
 		Amount:   5,
 		Time:     t4,
@@ -299,13 +348,15 @@ func TestProcessTransactionWithMultipleViolations_1(t *testing.T) {
 	status := AccountStatus{
 		account:    Account{ActiveCard: true, AvailableLimit: 65},
 		hasAccount: true,
+		initial:    Account{ActiveCard: true, AvailableLimit: 100},
 	}
 
 	expected := AccountOperationOutput{
 		Account:    Account{ActiveCard: true, AvailableLimit: 65},
 		Violations: []string{DoubledTransaction, HighFrequencySmallInterval},
+		Decision:   DecisionDeclined,
 	}
-	result := processTransaction(newOperation, status, operations)
+	result, _ := processTransaction(newOperation, status, newTestStorage(operations), DefaultRules())
 
 	if reflect.DeepEqual(expected, result) {
 		t.Logf("processTransaction(...) PASSED \nexpected: %v \nresult: %v", expected, result)
@@ -327,7 +378,7 @@ func TestProcessTransactionWithMultipleViolations_2(t *testing.T) {
 	t4, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T11:00:08.000Z", loc)
 	operations = append(operations, TransactionOperation{Transaction: Transaction{Merchant: "Burger King", Amount: 5, Time: t4}})
 	t5, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T11:00:18.000Z", loc)
-	newOperation := TransactionOperation{Transaction{
+	newOperation := TransactionOperation{Transaction: Transaction{
 		Merchant: "Burger King",
 		Amount:   150,
 		Time:     t5,
@@ -336,13 +387,15 @@ func TestProcessTransactionWithMultipleViolations_2(t *testing.T) {
 	status := AccountStatus{
 		account:    Account{ActiveCard: true, AvailableLimit: 65},
 		hasAccount: true,
+		initial:    Account{ActiveCard: true, AvailableLimit: 100},
 	}
 
 	expected := AccountOperationOutput{
 		Account:    Account{ActiveCard: true, AvailableLimit: 65},
 		Violations: []string{InsufficientLimit, HighFrequencySmallInterval},
+		Decision:   DecisionDeclined,
 	}
-	result := processTransaction(newOperation, status, operations)
+	result, _ := processTransaction(newOperation, status, newTestStorage(operations), DefaultRules())
 
 	if reflect.DeepEqual(expected, result) {
 		t.Logf("processTransaction(...) PASSED \nexpected: %v \nresult: %v", expected, result)
@@ -364,7 +417,7 @@ func TestProcessTransactionWithMultipleViolations_3(t *testing.T) {
 	t4, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T11:00:08.000Z", loc)
 	operations = append(operations, TransactionOperation{Transaction: Transaction{Merchant: "Burger King", Amount: 5, Time: t4}})
 	t5, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T11:00:18.000Z", loc)
-	newOperation := TransactionOperation{Transaction{
+	newOperation := TransactionOperation{Transaction: Transaction{
 		Merchant: "Burger King",
 		Amount:   150,
 		Time:     t5,
@@ -373,13 +426,15 @@ func TestProcessTransactionWithMultipleViolations_3(t *testing.T) {
 	status := AccountStatus{
 		account:    Account{ActiveCard: true, AvailableLimit: 65},
 		hasAccount: true,
+		initial:    Account{ActiveCard: true, AvailableLimit: 100},
 	}
 
 	expected := AccountOperationOutput{
 		Account:    Account{ActiveCard: true, AvailableLimit: 65},
 		Violations: []string{InsufficientLimit, HighFrequencySmallInterval},
+		Decision:   DecisionDeclined,
 	}
-	result := processTransaction(newOperation, status, operations)
+	result, _ := processTransaction(newOperation, status, newTestStorage(operations), DefaultRules())
 
 	if reflect.DeepEqual(expected, result) {
 		t.Logf("processTransaction(...) PASSED \nexpected: %v \nresult: %v", expected, result)
@@ -401,7 +456,7 @@ func TestProcessTransactionWithMultipleViolations_4(t *testing.T) {
 	t4, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T11:00:08.000Z", loc)
 	operations = append(operations, TransactionOperation{Transaction: Transaction{Merchant: "Burger King", Amount: 5, Time: t4}})
 	t5, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T11:00:18.000Z", loc)
-	newOperation := TransactionOperation{Transaction{
+	newOperation := TransactionOperation{Transaction: Transaction{
 		Merchant: "Burger King",
 		Amount:   150,
 		Time:     t5,
@@ -410,13 +465,15 @@ func TestProcessTransactionWithMultipleViolations_4(t *testing.T) {
 	status := AccountStatus{
 		account:    Account{ActiveCard: true, AvailableLimit: 65},
 		hasAccount: true,
+		initial:    Account{ActiveCard: true, AvailableLimit: 100},
 	}
 
 	expected := AccountOperationOutput{
 		Account:    Account{ActiveCard: true, AvailableLimit: 65},
 		Violations: []string{InsufficientLimit, HighFrequencySmallInterval},
+		Decision:   DecisionDeclined,
 	}
-	result := processTransaction(newOperation, status, operations)
+	result, _ := processTransaction(newOperation, status, newTestStorage(operations), DefaultRules())
 
 	if reflect.DeepEqual(expected, result) {
 		t.Logf("processTransaction(...) PASSED \nexpected: %v \nresult: %v", expected, result)
@@ -424,3 +481,193 @@ func TestProcessTransactionWithMultipleViolations_4(t *testing.T) {
 		t.Errorf("processTransaction(...) FAILED \nexpected: %v \nresult: %v", expected, result)
 	}
 }
+
+func TestStateAt(t *testing.T) {
+	input := `{"account": {"active-card": true, "available-limit": 100}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}
+{"transaction": {"merchant": "Habbib's", "amount": 90, "time": "2019-02-13T11:00:00.000Z"}}
+{"transaction": {"merchant": "McDonald's", "amount": 30, "time": "2019-02-13T12:00:00.000Z"}}`
+
+	at, _ := parseTime("2019-02-13T11:30:00.000Z", nil)
+
+	expected := AccountStatus{
+		account:    Account{ActiveCard: true, AvailableLimit: 80},
+		hasAccount: true,
+		initial:    Account{ActiveCard: true, AvailableLimit: 100},
+		profile:    Profile{Count: 1, Mean: 20, Merchants: map[string]int{"Burger King": 1}},
+	}
+	expected.profile.Hours[10] = 1
+	result, err := StateAt(strings.NewReader(input), "", at, StateOptions{})
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("StateAt(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("StateAt(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestStateAtBeforeAccount(t *testing.T) {
+	input := `{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}
+{"account": {"active-card": true, "available-limit": 100}}`
+
+	at, _ := parseTime("2019-02-13T09:00:00.000Z", nil)
+
+	// accounts have no time, so they are replayed whatever the moment
+	expected := AccountStatus{
+		account:    Account{ActiveCard: true, AvailableLimit: 100},
+		hasAccount: true,
+		initial:    Account{ActiveCard: true, AvailableLimit: 100},
+	}
+	result, err := StateAt(strings.NewReader(input), "", at, StateOptions{})
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("StateAt(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("StateAt(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestStateAtUnsorted(t *testing.T) {
+	input := `{"account": {"active-card": true, "available-limit": 100}}
+{"transaction": {"merchant": "McDonald's", "amount": 30, "time": "2019-02-13T12:00:00.000Z"}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}
+{"transaction": {"merchant": "Habbib's", "amount": 10, "time": "2019-02-13T11:00:00.000Z"}}`
+
+	at, _ := parseTime("2019-02-13T11:30:00.000Z", nil)
+
+	// the late transaction is skipped, the ones logged after it are still replayed
+	expected := 70
+	status, err := StateAt(strings.NewReader(input), "", at, StateOptions{})
+	result := status.Account().AvailableLimit
+
+	if err == nil && expected == result {
+		t.Logf("StateAt(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("StateAt(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestStateAtCSV(t *testing.T) {
+	input := `type,active-card,available-limit,merchant,amount,time
+account,true,100,,,
+transaction,,,Burger King,20,2019-02-13T10:00:00-03:00
+transaction,,,Habbib's,30,2019-02-13T12:00:00-03:00`
+
+	at, _ := parseTime("2019-02-13T14:00:00.000Z", nil)
+	location, err := time.LoadLocation("America/Sao_Paulo")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	// the transaction at 15:00 UTC is later than the moment
+	expected := 80
+	status, err := StateAt(strings.NewReader(input), "", at, StateOptions{InputFormat: FormatCSV, Location: location})
+	result := status.Account().AvailableLimit
+
+	if err == nil && expected == result {
+		t.Logf("StateAt(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("StateAt(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestStateAtRules(t *testing.T) {
+	input := `{"account": {"active-card": true, "available-limit": 100}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:30.000Z"}}`
+
+	at, _ := parseTime("2019-02-13T11:00:00.000Z", nil)
+	rules := DefaultRules()
+	rules.Policy.Severities = map[string]string{DoubledTransaction: SeverityWarnOnly}
+
+	// the doubled transaction is approved with the warn-only policy, declined with the default one
+	expected := []int{60, 80}
+	var result []int
+	for _, replayed := range []*Rules{&rules, nil} {
+		status, err := StateAt(strings.NewReader(input), "", at, StateOptions{Rules: replayed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, status.Account().AvailableLimit)
+	}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("StateAt(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("StateAt(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+type benchmarkRuleMix struct {
+	name  string
+	rules Rules
+}
+
+// benchmarkRules are the rule mixes benchmarks run with: the rules of the specification only,
+// the default rules, and the default rules with a fraud score model
+func benchmarkRules() []benchmarkRuleMix {
+	scoring := DefaultRules()
+	scoring.FraudScore = scoring.FraudScore.WithModel(Model{Bias: -3, Weights: map[string]float64{
+		"amount-ratio": 4, "seconds-since-last": -0.01, "new-merchant": 1, "recent-transactions": 0.5}})
+	return []benchmarkRuleMix{{"specification", Rules{}}, {"default", DefaultRules()}, {"scoring", scoring}}
+}
+
+// benchmarkHistory returns a storage whose account has size transactions at the same merchant 100ms apart,
+// all of them within the rule windows of the returned transaction
+func benchmarkHistory(size int) (Storage, Transaction) {
+	storage := NewMemoryStorage()
+	start := time.Date(2019, 2, 13, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < size; i++ {
+		_ = storage.AppendHistory(defaultAccountID, Transaction{
+			Merchant: "Burger King",
+			Amount:   i + 2,
+			Time:     start.Add(time.Duration(i) * time.Millisecond * 100),
+		})
+	}
+	return storage, Transaction{Merchant: "Burger King", Amount: 1, Time: start.Add(time.Duration(size) * time.Millisecond * 100)}
+}
+
+func BenchmarkHasDoubledTransaction(b *testing.B) {
+	for _, size := range []int{10, 100, 1000} {
+		storage, transaction := benchmarkHistory(size)
+		b.Run(fmt.Sprintf("history=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = hasDoubledTransaction(storage, defaultAccountID, transaction)
+			}
+		})
+	}
+}
+
+func BenchmarkHasHighFrequencySmallInterval(b *testing.B) {
+	pivot := Account{ActiveCard: true, AvailableLimit: 100}
+	for _, size := range []int{10, 100, 1000} {
+		storage, transaction := benchmarkHistory(size)
+		b.Run(fmt.Sprintf("history=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = hasHighFrequencySmallInterval(pivot, storage, defaultAccountID, transaction)
+			}
+		})
+	}
+}
+
+// BenchmarkProcess decides generated streams end to end, decoding and encoding included
+func BenchmarkProcess(b *testing.B) {
+	for _, size := range []int{1000, 10000} {
+		var stream bytes.Buffer
+		_ = Generate(&stream, nil, GenerateOptions{Seed: 1, Accounts: 10, Transactions: size, FraudRate: rate(0.05)})
+		input := stream.Bytes()
+
+		for _, mix := range benchmarkRules() {
+			rules := mix.rules
+			b.Run(fmt.Sprintf("operations=%d/rules=%s", size, mix.name), func(b *testing.B) {
+				b.SetBytes(int64(len(input)))
+				for i := 0; i < b.N; i++ {
+					err := New(Options{Rules: &rules}).Run(context.Background(), bytes.NewReader(input), io.Discard)
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package authorizer
+
+import (
+	"math"
+	"time"
+)
+
+const AnomalousTransaction = "anomalous-transaction"
+
+// maxProfileMerchants bounds the merchants kept in a profile, merchants seen once it is full are not kept
+const maxProfileMerchants = 100
+
+// Profile holds the statistics of the transactions approved for an account. The mean and the standard
+// deviation of the amounts are cumulative, unless the profile is built with a window: once it has more
+// transactions than the window, each new one weighs 1/window and the older ones fade exponentially.
+// The hours and the merchants are always counted since the first transaction.
+type Profile struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	// M2 is the sum of the squared differences from the mean, as in Welford's algorithm
+	M2 float64 `json:"m2"`
+	// Window the mean and M2 were weighted with, cumulative when zero
+	Window    int            `json:"window,omitempty"`
+	Hours     [24]int        `json:"hours"`
+	Merchants map[string]int `json:"merchants"`
+}
+
+// add returns the profile updated with an approved transaction, leaving the original untouched,
+// window being the one of the anomaly rule
+func (profile Profile) add(transaction Transaction, window int) Profile {
+	amount := float64(transaction.Amount)
+	profile.Count++
+	profile.Window = window
+	weight := float64(profile.weight())
+	if profile.weight() < profile.Count {
+		// the squared differences already summed fade as the mean moves with a fixed weight
+		profile.M2 *= 1 - 1/weight
+	}
+	delta := amount - profile.Mean
+	profile.Mean += delta / weight
+	profile.M2 += delta * (amount - profile.Mean)
+
+	profile.Hours[transaction.Time.(time.Time).UTC().Hour()]++
+
+	merchants := make(map[string]int, len(profile.Merchants)+1)
+	for merchant, count := range profile.Merchants {
+		merchants[merchant] = count
+	}
+	if _, ok := merchants[transaction.Merchant]; ok || len(merchants) < maxProfileMerchants {
+		merchants[transaction.Merchant]++
+	}
+	profile.Merchants = merchants
+	return profile
+}
+
+// weight is the number of transactions the mean and M2 stand for, at most the window
+func (profile Profile) weight() int {
+	if profile.Window > 0 && profile.Window < profile.Count {
+		return profile.Window
+	}
+	return profile.Count
+}
+
+// StdDev returns the standard deviation of the amounts
+func (profile Profile) StdDev() float64 {
+	if profile.weight() < 2 {
+		return 0
+	}
+	return math.Sqrt(profile.M2 / float64(profile.weight()-1))
+}
+
+// score returns how far a transaction is from the profile: the distance of its amount from the mean in
+// standard deviations (at least 1), plus 1 for an hour and 1 for a merchant never seen before
+func (profile Profile) score(transaction Transaction) float64 {
+	score := math.Abs(float64(transaction.Amount)-profile.Mean) / math.Max(profile.StdDev(), 1)
+	if profile.Hours[transaction.Time.(time.Time).UTC().Hour()] == 0 {
+		score++
+	}
+	if profile.Merchants[transaction.Merchant] == 0 {
+		score++
+	}
+	return math.Round(score*100) / 100
+}
+
+// AnomalyRule scores a transaction against the profile of its account once it has MinTransactions
+// approved transactions, flagging it when the score reaches Threshold. With a Window, the amounts of
+// the profile are weighted towards the last Window transactions rather than all of them.
+type AnomalyRule struct {
+	MinTransactions int     `json:"min-transactions"`
+	Threshold       float64 `json:"threshold"`
+	Window          int     `json:"window"`
+}
+
+func (rule AnomalyRule) enabled() bool {
+	return rule.MinTransactions > 0 && rule.Threshold > 0
+}
+
+// anomalyScore returns the score of a transaction and whether it was scored at all
+func anomalyScore(rule AnomalyRule, profile Profile, transaction Transaction) (float64, bool) {
+	if !rule.enabled() || profile.Count < rule.MinTransactions {
+		return 0, false
+	}
+	return profile.score(transaction), true
+}
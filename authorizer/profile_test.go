@@ -0,0 +1,138 @@
+package authorizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testProfile() Profile {
+	var profile Profile
+	for i, amount := range []int{20, 30, 40} {
+		profile = profile.add(Transaction{
+			Merchant: []string{"Shop A", "Shop B", "Shop A"}[i],
+			Amount:   amount,
+			Time:     time.Date(2019, 2, 13, 10, i, 0, 0, time.UTC),
+		}, 0)
+	}
+	return profile
+}
+
+func TestProfileAdd(t *testing.T) {
+	profile := testProfile()
+
+	expected := []interface{}{3, 30.0, 10.0, 3, map[string]int{"Shop A": 2, "Shop B": 1}}
+	result := []interface{}{profile.Count, profile.Mean, profile.StdDev(), profile.Hours[10], profile.Merchants}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("Profile.add(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Profile.add(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestProfileAddWindow(t *testing.T) {
+	// cumulative, the mean is still halfway; weighted towards the last 2, it follows the new amounts
+	expected := []float64{55, 21.25}
+	var result []float64
+	for _, window := range []int{0, 2} {
+		var profile Profile
+		for i, amount := range []int{100, 100, 100, 10, 10, 10} {
+			profile = profile.add(Transaction{Merchant: "Shop A", Amount: amount,
+				Time: time.Date(2019, 2, 13, 10, i, 0, 0, time.UTC)}, window)
+		}
+		result = append(result, profile.Mean)
+	}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("Profile.add(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Profile.add(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestProfileScore(t *testing.T) {
+	profile := testProfile()
+	at := time.Date(2019, 2, 13, 10, 30, 0, 0, time.UTC)
+	transactions := []Transaction{
+		// the mean, at a usual hour and merchant
+		{Merchant: "Shop A", Amount: 30, Time: at},
+		// two standard deviations away
+		{Merchant: "Shop B", Amount: 50, Time: at},
+		// at an hour and a merchant never seen
+		{Merchant: "Shop C", Amount: 30, Time: at.Add(5 * time.Hour)},
+	}
+
+	expected := []float64{0, 2, 2}
+	var result []float64
+	for _, transaction := range transactions {
+		result = append(result, profile.score(transaction))
+	}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("Profile.score(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Profile.score(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestAnomalyScoreNotScored(t *testing.T) {
+	transaction := Transaction{Merchant: "Shop C", Amount: 1000, Time: time.Date(2019, 2, 13, 3, 0, 0, 0, time.UTC)}
+	rules := []AnomalyRule{{MinTransactions: 4, Threshold: 5}, {}}
+
+	for _, rule := range rules {
+		score, scored := anomalyScore(rule, testProfile(), transaction)
+
+		if !scored && score == 0 {
+			t.Logf("anomalyScore(...) PASSED \nexpected: %v \nresult: %v", false, scored)
+		} else {
+			t.Errorf("anomalyScore(...) FAILED \nexpected: %v \nresult: %v %v", false, scored, score)
+		}
+	}
+}
+
+func TestAccountStatusJSONProfile(t *testing.T) {
+	expected := AccountStatus{account: Account{ActiveCard: true, AvailableLimit: 10}, hasAccount: true, profile: testProfile()}
+
+	var result AccountStatus
+	jsonData, err := json.Marshal(expected)
+	if err == nil {
+		err = json.Unmarshal(jsonData, &result)
+	}
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("AccountStatus.UnmarshalJSON(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("AccountStatus.UnmarshalJSON(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestAuthorizerRunAnomaly(t *testing.T) {
+	input := `{"account": {"active-card": true, "available-limit": 1000}}
+{"transaction": {"merchant": "Shop A", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}
+{"transaction": {"merchant": "Shop B", "amount": 30, "time": "2019-02-13T10:10:00.000Z"}}
+{"transaction": {"merchant": "Shop A", "amount": 30, "time": "2019-02-13T10:20:00.000Z"}}
+{"transaction": {"merchant": "Shop C", "amount": 200, "time": "2019-02-14T03:00:00.000Z"}}`
+
+	expected := `{"account":{"active-card":true,"available-limit":1000},"violations":[],"decision":"approved","id":"1"}
+{"account":{"active-card":true,"available-limit":980},"violations":[],"decision":"approved","id":"2"}
+{"account":{"active-card":true,"available-limit":950},"violations":[],"decision":"approved","id":"3"}
+{"account":{"active-card":true,"available-limit":920},"violations":[],"decision":"approved","anomaly-score":0.71,"id":"4"}
+{"account":{"active-card":true,"available-limit":920},"violations":["anomalous-transaction"],"decision":"declined","anomaly-score":32.02,"id":"5"}
+`
+	rules := DefaultRules()
+	rules.Anomaly = AnomalyRule{MinTransactions: 2, Threshold: 5}
+	var writer bytes.Buffer
+	err := New(Options{Rules: &rules}).Run(context.Background(), strings.NewReader(input), &writer)
+	result := writer.String()
+
+	if err == nil && expected == result {
+		t.Logf("Authorizer.Run(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Authorizer.Run(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
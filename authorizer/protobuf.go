@@ -0,0 +1,444 @@
+package authorizer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// protocol buffers wire types, see proto/authorizer.proto for the messages
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// maxProtoMessage bounds the length of a delimited message
+const maxProtoMessage = 4 << 20
+
+var errProtoTruncated = errors.New("protobuf: message truncated")
+
+type protoWriter struct {
+	buffer []byte
+}
+
+func (writer *protoWriter) uvarint(value uint64) {
+	var data [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(data[:], value)
+	writer.buffer = append(writer.buffer, data[:n]...)
+}
+
+func (writer *protoWriter) tag(field int, wire int) {
+	writer.uvarint(uint64(field<<3 | wire))
+}
+
+func (writer *protoWriter) int64(field int, value int64) {
+	if value != 0 {
+		writer.tag(field, wireVarint)
+		writer.uvarint(uint64(value))
+	}
+}
+
+func (writer *protoWriter) double(field int, value float64) {
+	if value != 0 {
+		writer.tag(field, wireFixed64)
+		var data [8]byte
+		binary.LittleEndian.PutUint64(data[:], math.Float64bits(value))
+		writer.buffer = append(writer.buffer, data[:]...)
+	}
+}
+
+func (writer *protoWriter) bool(field int, value bool) {
+	if value {
+		writer.tag(field, wireVarint)
+		writer.uvarint(1)
+	}
+}
+
+func (writer *protoWriter) bytes(field int, value []byte) {
+	writer.tag(field, wireBytes)
+	writer.uvarint(uint64(len(value)))
+	writer.buffer = append(writer.buffer, value...)
+}
+
+func (writer *protoWriter) string(field int, value string) {
+	if value != "" {
+		writer.bytes(field, []byte(value))
+	}
+}
+
+type protoField struct {
+	number int
+	wire   int
+	varint uint64 // value of varint and fixed64 fields
+	bytes  []byte
+}
+
+// readProtoFields calls fn for every field of a message, skipping fixed32 fields
+func readProtoFields(data []byte, fn func(field protoField) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errProtoTruncated
+		}
+		data = data[n:]
+
+		field := protoField{number: int(key >> 3), wire: int(key & 7)}
+		switch field.wire {
+		case wireVarint:
+			field.varint, n = binary.Uvarint(data)
+			if n <= 0 {
+				return errProtoTruncated
+			}
+			data = data[n:]
+		case wireBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || length > uint64(len(data)-n) {
+				return errProtoTruncated
+			}
+			field.bytes = data[n : n+int(length)]
+			data = data[n+int(length):]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errProtoTruncated
+			}
+			field.varint = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errProtoTruncated
+			}
+			data = data[4:]
+			continue
+		default:
+			return fmt.Errorf("protobuf: wire type %d not supported", field.wire)
+		}
+
+		if err := fn(field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func marshalAccount(account Account) []byte {
+	var writer protoWriter
+	writer.string(1, account.ID)
+	writer.bool(2, account.ActiveCard)
+	writer.int64(3, int64(account.AvailableLimit))
+	return writer.buffer
+}
+
+func unmarshalAccount(data []byte) (Account, error) {
+	var account Account
+	err := readProtoFields(data, func(field protoField) error {
+		switch field.number {
+		case 1:
+			account.ID = string(field.bytes)
+		case 2:
+			account.ActiveCard = field.varint != 0
+		case 3:
+			account.AvailableLimit = int(int64(field.varint))
+		}
+		return nil
+	})
+	return account, err
+}
+
+func marshalTransaction(transaction Transaction) ([]byte, error) {
+	var writer protoWriter
+	writer.string(1, transaction.AccountID)
+	writer.string(2, transaction.Merchant)
+	writer.int64(3, int64(transaction.Amount))
+
+	value, ok := transaction.Time.(time.Time)
+	if data, isString := transaction.Time.(string); isString {
+		parsed, err := parseTime(data, nil)
+		if err != nil {
+			return nil, err
+		}
+		value, ok = parsed, true
+	}
+	if ok {
+		var timestamp protoWriter
+		timestamp.int64(1, value.Unix())
+		timestamp.int64(2, int64(value.Nanosecond()))
+		writer.bytes(4, timestamp.buffer)
+	}
+	writer.string(5, transaction.MCC)
+
+	return writer.buffer, nil
+}
+
+func unmarshalTransaction(data []byte) (Transaction, error) {
+	var transaction Transaction
+	err := readProtoFields(data, func(field protoField) error {
+		switch field.number {
+		case 1:
+			transaction.AccountID = string(field.bytes)
+		case 2:
+			transaction.Merchant = string(field.bytes)
+		case 3:
+			transaction.Amount = int(int64(field.varint))
+		case 4:
+			var seconds, nanos int64
+			err := readProtoFields(field.bytes, func(field protoField) error {
+				switch field.number {
+				case 1:
+					seconds = int64(field.varint)
+				case 2:
+					nanos = int64(int32(field.varint))
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			transaction.Time = time.Unix(seconds, nanos).UTC()
+		case 5:
+			transaction.MCC = string(field.bytes)
+		}
+		return nil
+	})
+	return transaction, err
+}
+
+// MarshalOperation encodes an Operation message
+func MarshalOperation(operation Operation) ([]byte, error) {
+	var writer protoWriter
+	if operation.Account != nil {
+		writer.bytes(1, marshalAccount(*operation.Account))
+	}
+	if operation.Transaction != nil {
+		transaction, err := marshalTransaction(*operation.Transaction)
+		if err != nil {
+			return nil, err
+		}
+		writer.bytes(2, transaction)
+	}
+	writer.string(3, operation.ID)
+	return writer.buffer, nil
+}
+
+// UnmarshalOperation decodes an Operation message
+func UnmarshalOperation(data []byte) (Operation, error) {
+	var operation Operation
+	err := readProtoFields(data, func(field protoField) error {
+		switch field.number {
+		case 1:
+			account, err := unmarshalAccount(field.bytes)
+			if err != nil {
+				return err
+			}
+			operation.Account, operation.Transaction = &account, nil
+		case 2:
+			transaction, err := unmarshalTransaction(field.bytes)
+			if err != nil {
+				return err
+			}
+			operation.Account, operation.Transaction = nil, &transaction
+		case 3:
+			operation.ID = string(field.bytes)
+		}
+		return nil
+	})
+	return operation, err
+}
+
+// MarshalOutput encodes an AccountOperationOutput message
+func MarshalOutput(output AccountOperationOutput) []byte {
+	var writer protoWriter
+	writer.bytes(1, marshalAccount(output.Account))
+	for _, violation := range output.Violations {
+		writer.bytes(2, []byte(violation))
+	}
+	writer.string(3, output.ID)
+	writer.double(4, output.AnomalyScore)
+	writer.double(5, output.FraudScore)
+	writer.string(6, output.Decision)
+	writer.string(7, output.ReviewID)
+	return writer.buffer
+}
+
+// UnmarshalOutput decodes an AccountOperationOutput message
+func UnmarshalOutput(data []byte) (AccountOperationOutput, error) {
+	var output AccountOperationOutput
+	err := readProtoFields(data, func(field protoField) error {
+		switch field.number {
+		case 1:
+			account, err := unmarshalAccount(field.bytes)
+			if err != nil {
+				return err
+			}
+			output.Account = account
+		case 2:
+			output.Violations = append(output.Violations, string(field.bytes))
+		case 3:
+			output.ID = string(field.bytes)
+		case 4:
+			output.AnomalyScore = math.Float64frombits(field.varint)
+		case 5:
+			output.FraudScore = math.Float64frombits(field.varint)
+		case 6:
+			output.Decision = string(field.bytes)
+		case 7:
+			output.ReviewID = string(field.bytes)
+		}
+		return nil
+	})
+	return output, err
+}
+
+func marshalReview(review Review) ([]byte, error) {
+	transaction, err := marshalTransaction(review.Transaction)
+	if err != nil {
+		return nil, err
+	}
+	var writer protoWriter
+	writer.string(1, review.ID)
+	writer.string(2, review.AccountID)
+	writer.bytes(3, transaction)
+	for _, violation := range review.Violations {
+		writer.bytes(4, []byte(violation))
+	}
+	return writer.buffer, nil
+}
+
+func unmarshalReview(data []byte) (Review, error) {
+	var review Review
+	err := readProtoFields(data, func(field protoField) error {
+		switch field.number {
+		case 1:
+			review.ID = string(field.bytes)
+		case 2:
+			review.AccountID = string(field.bytes)
+		case 3:
+			transaction, err := unmarshalTransaction(field.bytes)
+			if err != nil {
+				return err
+			}
+			review.Transaction = transaction
+		case 4:
+			review.Violations = append(review.Violations, string(field.bytes))
+		}
+		return nil
+	})
+	return review, err
+}
+
+// MarshalReviews encodes a ReviewList message
+func MarshalReviews(reviews []Review) ([]byte, error) {
+	var writer protoWriter
+	for _, review := range reviews {
+		data, err := marshalReview(review)
+		if err != nil {
+			return nil, err
+		}
+		writer.bytes(1, data)
+	}
+	return writer.buffer, nil
+}
+
+// UnmarshalReviews decodes a ReviewList message
+func UnmarshalReviews(data []byte) ([]Review, error) {
+	var reviews []Review
+	err := readProtoFields(data, func(field protoField) error {
+		if field.number != 1 {
+			return nil
+		}
+		review, err := unmarshalReview(field.bytes)
+		if err != nil {
+			return err
+		}
+		reviews = append(reviews, review)
+		return nil
+	})
+	return reviews, err
+}
+
+// MarshalReviewRequest encodes a ReviewRequest message
+func MarshalReviewRequest(id string) []byte {
+	var writer protoWriter
+	writer.string(1, id)
+	return writer.buffer
+}
+
+// UnmarshalReviewRequest decodes a ReviewRequest message, returning the id of the review
+func UnmarshalReviewRequest(data []byte) (string, error) {
+	var id string
+	err := readProtoFields(data, func(field protoField) error {
+		if field.number == 1 {
+			id = string(field.bytes)
+		}
+		return nil
+	})
+	return id, err
+}
+
+// protobufDecoder reads length-delimited Operation messages
+type protobufDecoder struct {
+	reader *bufio.Reader
+	done   bool
+}
+
+func (decoder *protobufDecoder) Read() (interface{}, error) {
+	if decoder.done {
+		return nil, io.EOF
+	}
+
+	length, err := binary.ReadUvarint(decoder.reader)
+	if err != nil {
+		decoder.done = true
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	if length > maxProtoMessage {
+		// the stream cannot be resynchronized after a bad length
+		decoder.done = true
+		return nil, fmt.Errorf("protobuf: message of %d bytes exceeds %d bytes", length, maxProtoMessage)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(decoder.reader, data); err != nil {
+		decoder.done = true
+		return nil, errProtoTruncated
+	}
+
+	return data, nil
+}
+
+func (decoder *protobufDecoder) Decode(record interface{}) (interface{}, error) {
+	operation, err := UnmarshalOperation(record.([]byte))
+	if err != nil {
+		return nil, err
+	}
+	return fromOperation(operation, nil)
+}
+
+// protobufEncoder writes length-delimited AccountOperationOutput messages
+type protobufEncoder struct {
+	writer *bufio.Writer
+}
+
+func (encoder *protobufEncoder) Encode(output AccountOperationOutput) error {
+	data := MarshalOutput(output)
+
+	var length [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(length[:], uint64(len(data)))
+	if _, err := encoder.writer.Write(length[:n]); err != nil {
+		return err
+	}
+	_, err := encoder.writer.Write(data)
+	return err
+}
+
+func (encoder *protobufEncoder) Flush() error {
+	return encoder.writer.Flush()
+}
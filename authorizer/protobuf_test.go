@@ -0,0 +1,149 @@
+package authorizer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarshalOperation(t *testing.T) {
+	expected := Operation{ID: "op-1", Transaction: &Transaction{
+		AccountID: "a", Merchant: "Burger King", Amount: 20,
+		Time: time.Date(2019, 2, 13, 10, 0, 0, 500, time.UTC), MCC: "5814",
+	}}
+
+	data, err := MarshalOperation(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := UnmarshalOperation(data)
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("UnmarshalOperation(MarshalOperation(...)) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("UnmarshalOperation(MarshalOperation(...)) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestMarshalOperationWire(t *testing.T) {
+	// bytes as produced by protoc generated code for the same message
+	expected := []byte{0x0a, 0x04, 0x10, 0x01, 0x18, 0x64}
+
+	result, err := MarshalOperation(Operation{Account: &Account{ActiveCard: true, AvailableLimit: 100}})
+
+	if err == nil && bytes.Equal(expected, result) {
+		t.Logf("MarshalOperation(...) PASSED \nexpected: %x \nresult: %x", expected, result)
+	} else {
+		t.Errorf("MarshalOperation(...) FAILED \nexpected: %x \nresult: %x %v", expected, result, err)
+	}
+}
+
+func TestMarshalOutput(t *testing.T) {
+	expected := AccountOperationOutput{
+		Account:      Account{ID: "a", ActiveCard: true, AvailableLimit: -10},
+		Violations:   []string{"insufficient-limit", "doubled-transaction"},
+		Decision:     DecisionReview,
+		ReviewID:     "a@2019-02-13T11:00:00Z",
+		AnomalyScore: 3.25,
+		FraudScore:   0.125,
+		ID:           "op-1",
+	}
+
+	result, err := UnmarshalOutput(MarshalOutput(expected))
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("UnmarshalOutput(MarshalOutput(...)) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("UnmarshalOutput(MarshalOutput(...)) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestMarshalReviews(t *testing.T) {
+	expected := []Review{
+		{ID: "r-1", AccountID: "a", Violations: []string{"high-amount"}, Transaction: Transaction{
+			AccountID: "a", Merchant: "Burger King", Amount: 900, Time: time.Date(2019, 2, 13, 11, 0, 0, 0, time.UTC)}},
+		{ID: "b@2019-02-13T11:00:01Z", AccountID: "b", Violations: []string{"anomaly"}, Transaction: Transaction{
+			AccountID: "b", Merchant: "Habbib's", Amount: 20, Time: time.Date(2019, 2, 13, 11, 0, 1, 0, time.UTC)}},
+	}
+
+	data, err := MarshalReviews(expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := UnmarshalReviews(data)
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("UnmarshalReviews(MarshalReviews(...)) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("UnmarshalReviews(MarshalReviews(...)) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestUnmarshalOperationTruncated(t *testing.T) {
+	data, _ := MarshalOperation(Operation{Account: &Account{ActiveCard: true, AvailableLimit: 100}})
+
+	_, err := UnmarshalOperation(data[:len(data)-1])
+
+	if err != nil {
+		t.Logf("UnmarshalOperation(...) PASSED \nexpected: error \nresult: %v", err)
+	} else {
+		t.Errorf("UnmarshalOperation(...) FAILED \nexpected: error \nresult: %v", err)
+	}
+}
+
+func delimited(messages ...[]byte) []byte {
+	var buffer bytes.Buffer
+	for _, message := range messages {
+		var length [binary.MaxVarintLen64]byte
+		buffer.Write(length[:binary.PutUvarint(length[:], uint64(len(message)))])
+		buffer.Write(message)
+	}
+	return buffer.Bytes()
+}
+
+func TestAuthorizerRunProtobuf(t *testing.T) {
+	account, _ := MarshalOperation(Operation{Account: &Account{ActiveCard: true, AvailableLimit: 100}})
+	transaction, _ := MarshalOperation(Operation{Transaction: &Transaction{
+		Merchant: "Burger King", Amount: 20, Time: "2019-02-13T10:00:00.000Z"}})
+	input := delimited(account, transaction)
+
+	expected := delimited(
+		MarshalOutput(AccountOperationOutput{Account: Account{ActiveCard: true, AvailableLimit: 100}, Decision: DecisionApproved, ID: "1"}),
+		MarshalOutput(AccountOperationOutput{Account: Account{ActiveCard: true, AvailableLimit: 80}, Decision: DecisionApproved, ID: "2"}),
+	)
+
+	var writer bytes.Buffer
+	err := New(Options{InputFormat: FormatProtobuf, OutputFormat: FormatProtobuf}).
+		Run(context.Background(), bytes.NewReader(input), &writer)
+	result := writer.Bytes()
+
+	if err == nil && bytes.Equal(expected, result) {
+		t.Logf("Authorizer.Run(...) PASSED \nexpected: %x \nresult: %x", expected, result)
+	} else {
+		t.Errorf("Authorizer.Run(...) FAILED \nexpected: %x \nresult: %x %v", expected, result, err)
+	}
+}
+
+func TestAuthorizerRunProtobufOutput(t *testing.T) {
+	input := `{"account": {"active-card": true, "available-limit": 100}}`
+
+	var writer bytes.Buffer
+	err := New(Options{OutputFormat: FormatProtobuf}).Run(context.Background(), strings.NewReader(input), &writer)
+
+	decoder := &protobufDecoder{reader: bufio.NewReader(&writer)}
+	record, _ := decoder.Read()
+	data, _ := record.([]byte)
+	result, _ := UnmarshalOutput(data)
+	expected := AccountOperationOutput{Account: Account{ActiveCard: true, AvailableLimit: 100}, Decision: DecisionApproved, ID: "1"}
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("Authorizer.Run(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Authorizer.Run(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
@@ -0,0 +1,196 @@
+package authorizer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const replHelp = `operations are json lines, as in the input, commands are:
+  :state [account-id]    status of an account
+  :history [account-id]  transactions kept in history for an account
+  :undo                  revert the last operation
+  :reset                 forget every account
+  :load <file>           apply every operation of a json lines file
+  :help                  show this help
+  :quit                  leave`
+
+type replOperation struct {
+	line      string
+	operation interface{}
+}
+
+// repl applies operations one at a time against in-memory state, keeping them so they can be undone
+type repl struct {
+	rules      Rules
+	retention  time.Duration
+	maxHistory int
+	location   *time.Location
+	storage    *RetentionStorage
+	applied    []replOperation
+	writer     io.Writer
+}
+
+// RunREPL reads operations and commands from reader and writes every decision to writer as soon as it is made.
+// State is kept in memory, only the Rules, Retention, MaxHistory and Location options are used.
+func RunREPL(reader io.Reader, writer io.Writer, options Options) error {
+	rules := DefaultRules()
+	if options.Rules != nil {
+		rules = *options.Rules
+	}
+	retention := options.Retention
+	if retention <= 0 {
+		retention = defaultRetention(rules)
+	}
+
+	session := &repl{rules: rules, retention: retention, maxHistory: options.MaxHistory, location: options.Location, writer: writer}
+	session.reset()
+
+	fmt.Fprintln(writer, `type an operation or :help`)
+	scanner := bufio.NewScanner(reader)
+	for {
+		fmt.Fprint(writer, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(writer)
+			return scanner.Err()
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == ":quit" || line == ":exit" {
+			return nil
+		}
+		if err := session.execute(line); err != nil {
+			return err
+		}
+	}
+}
+
+func (session *repl) reset() {
+	session.storage = NewRetentionStorage(NewMemoryStorage(), session.retention, session.maxHistory)
+	session.applied = nil
+}
+
+// execute runs a command or applies an operation, only storage failures are returned
+func (session *repl) execute(line string) error {
+	command, argument := line, ""
+	if i := strings.IndexByte(line, ' '); i > 0 {
+		command, argument = line[:i], strings.TrimSpace(line[i+1:])
+	}
+
+	switch command {
+	case "":
+		return nil
+	case ":help":
+		fmt.Fprintln(session.writer, replHelp)
+	case ":state":
+		status, err := session.storage.GetAccount(replAccountID(argument))
+		if err != nil {
+			return err
+		}
+		jsonData, _ := json.Marshal(status)
+		fmt.Fprintln(session.writer, string(jsonData))
+	case ":history":
+		history, err := session.storage.QueryHistory(replAccountID(argument), time.Time{}, time.Time{})
+		if err != nil {
+			return err
+		}
+		if len(history) == 0 {
+			fmt.Fprintln(session.writer, "no transactions")
+		}
+		for _, transaction := range history {
+			jsonData, _ := json.Marshal(transaction)
+			fmt.Fprintln(session.writer, string(jsonData))
+		}
+	case ":undo":
+		return session.undo()
+	case ":reset":
+		session.reset()
+		fmt.Fprintln(session.writer, "state reset")
+	case ":load":
+		return session.load(argument)
+	default:
+		if strings.HasPrefix(command, ":") {
+			fmt.Fprintf(session.writer, "unknown command %s, see :help\n", command)
+			return nil
+		}
+		return session.apply(line)
+	}
+	return nil
+}
+
+func replAccountID(id string) string {
+	if id == "" {
+		return defaultAccountID
+	}
+	return id
+}
+
+// apply decides an operation, its id being its position in the session when it has none
+func (session *repl) apply(line string) error {
+	operation, err := decode(line, session.location)
+	if err != nil {
+		fmt.Fprintf(session.writer, "error: %v\n", err)
+		return nil
+	}
+	operation = withLineID(operation, len(session.applied)+1)
+
+	output, err := apply(operation, session.storage, session.rules)
+	if err != nil {
+		return err
+	}
+	session.applied = append(session.applied, replOperation{line, operation})
+	fmt.Fprintln(session.writer, formatOutput(output))
+	return nil
+}
+
+// undo rebuilds the state by replaying every operation but the last, history eviction included
+func (session *repl) undo() error {
+	if len(session.applied) == 0 {
+		fmt.Fprintln(session.writer, "nothing to undo")
+		return nil
+	}
+
+	applied := session.applied[:len(session.applied)-1]
+	undone := session.applied[len(session.applied)-1]
+	session.reset()
+	for _, item := range applied {
+		if _, err := apply(item.operation, session.storage, session.rules); err != nil {
+			return err
+		}
+	}
+	session.applied = applied
+
+	fmt.Fprintf(session.writer, "undone %s\n", undone.line)
+	return nil
+}
+
+func (session *repl) load(path string) error {
+	if path == "" {
+		fmt.Fprintln(session.writer, "usage: :load <file>")
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Fprintf(session.writer, "error: %v\n", err)
+		return nil
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			if err := session.apply(line); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(session.writer, "error: %v\n", err)
+	}
+	return nil
+}
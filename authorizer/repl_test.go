@@ -0,0 +1,83 @@
+package authorizer
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunREPL(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "operations")
+	_ = os.WriteFile(path, []byte(`{"account": {"account-id": "b", "active-card": true, "available-limit": 50}}
+{"transaction": {"account-id": "b", "merchant": "Habbib's", "amount": 90, "time": "2019-02-13T11:00:00.000Z"}}
+`), 0o644)
+
+	input := `{"account": {"active-card": true, "available-limit": 100}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}
+{"transaction": {"merchant": "Burger King", "amount": -20}}
+:history
+:undo
+:history
+:state
+:load ` + path + `
+:state b
+:reset
+:state
+:undo
+:unknown
+:quit
+{"account": {"active-card": true, "available-limit": 100}}
+`
+
+	expected := `type an operation or :help
+> {"account":{"active-card":true,"available-limit":100},"violations":[],"decision":"approved","id":"1"}
+> {"account":{"active-card":true,"available-limit":80},"violations":[],"decision":"approved","id":"2"}
+> error: transaction.time: is required
+> {"merchant":"Burger King","amount":20,"time":"2019-02-13T10:00:00Z"}
+> undone {"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}
+> no transactions
+> {"account":{"active-card":true,"available-limit":100},"has-account":true,"initial-account":{"active-card":true,"available-limit":100}}
+> {"account":{"account-id":"b","active-card":true,"available-limit":50},"violations":[],"decision":"approved","id":"2"}
+{"account":{"account-id":"b","active-card":true,"available-limit":50},"violations":["insufficient-limit"],"decision":"declined","id":"3"}
+> {"account":{"account-id":"b","active-card":true,"available-limit":50},"has-account":true,"initial-account":{"account-id":"b","active-card":true,"available-limit":50}}
+> state reset
+> {"account":{"active-card":false,"available-limit":0},"has-account":false,"initial-account":{"active-card":false,"available-limit":0}}
+> nothing to undo
+> unknown command :unknown, see :help
+> `
+
+	var writer bytes.Buffer
+	err := RunREPL(strings.NewReader(input), &writer, Options{})
+	result := writer.String()
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("RunREPL(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("RunREPL(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestRunREPLUndoHistory(t *testing.T) {
+	input := `{"account": {"active-card": true, "available-limit": 100}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:30.000Z"}}
+:undo
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:30.000Z"}}
+`
+
+	var writer bytes.Buffer
+	err := RunREPL(strings.NewReader(input), &writer, Options{})
+	lines := strings.Split(writer.String(), "\n")
+	// the doubled transaction is decided again the same way once undone
+	expected := `> {"account":{"active-card":true,"available-limit":80},"violations":["doubled-transaction"],"decision":"declined","id":"3"}`
+	result := lines[len(lines)-3]
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("RunREPL(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("RunREPL(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
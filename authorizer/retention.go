@@ -0,0 +1,81 @@
+package authorizer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RetentionStorage bounds the history kept by a storage: every time a transaction is appended,
+// the ones older than the retention window and above the per account ceiling are evicted
+type RetentionStorage struct {
+	Storage
+	retention  time.Duration
+	maxHistory int
+	// clock tells the time the retention window ends at, the time of the transaction appended when nil
+	clock   Clock
+	mutex   sync.Mutex
+	evicted int
+}
+
+type RetentionStats struct {
+	Retention  time.Duration
+	MaxHistory int
+	Retained   int
+	Evicted    int
+}
+
+// defaultRetention is the largest window any rule looks back into the history
+func defaultRetention(rules Rules) time.Duration {
+	var retention time.Duration
+	for _, window := range append(ruleWindows(), rules.windows()...) {
+		if window > retention {
+			retention = window
+		}
+	}
+	return retention
+}
+
+func NewRetentionStorage(storage Storage, retention time.Duration, maxHistory int) *RetentionStorage {
+	return &RetentionStorage{
+		Storage:    storage,
+		retention:  retention,
+		maxHistory: maxHistory,
+	}
+}
+
+func (storage *RetentionStorage) AppendHistory(id string, transaction Transaction) error {
+	if err := storage.Storage.AppendHistory(id, transaction); err != nil {
+		return err
+	}
+
+	now := transaction.Time.(time.Time)
+	if storage.clock != nil {
+		now = storage.clock.Tick(now)
+	}
+	before := now.Add(-storage.retention)
+	evicted, err := storage.Storage.EvictHistory(id, before, storage.maxHistory)
+
+	storage.mutex.Lock()
+	storage.evicted += evicted
+	storage.mutex.Unlock()
+
+	return err
+}
+
+func (storage *RetentionStorage) Stats() RetentionStats {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	return RetentionStats{
+		Retention:  storage.retention,
+		MaxHistory: storage.maxHistory,
+		Retained:   storage.HistorySize(),
+		Evicted:    storage.evicted,
+	}
+}
+
+func (stats RetentionStats) String() string {
+	return fmt.Sprintf("history retention=%v max-history=%d retained=%d evicted=%d",
+		stats.Retention, stats.MaxHistory, stats.Retained, stats.Evicted)
+}
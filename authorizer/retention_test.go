@@ -0,0 +1,67 @@
+package authorizer
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testRetentionStorage(t *testing.T, backend Storage) {
+	// without the configurable rules, the largest window is the two minutes of the built-in ones
+	storage := NewRetentionStorage(backend, defaultRetention(Rules{}), 0)
+	for _, transaction := range testHistory() {
+		_ = storage.AppendHistory(defaultAccountID, transaction)
+	}
+
+	// the last transaction is more than two minutes after all the others
+	expected := RetentionStats{
+		Retention:  2 * time.Minute,
+		MaxHistory: 0,
+		Retained:   1,
+		Evicted:    3,
+	}
+	result := storage.Stats()
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("RetentionStorage.Stats() PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("RetentionStorage.Stats() FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestRetentionMemoryStorage(t *testing.T) {
+	testRetentionStorage(t, NewMemoryStorage())
+}
+
+func TestRetentionFileStorage(t *testing.T) {
+	backend, err := OpenFileStorage(filepath.Join(t.TempDir(), "state.kv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	testRetentionStorage(t, backend)
+}
+
+func TestRetentionMaxHistory(t *testing.T) {
+	storage := NewRetentionStorage(NewMemoryStorage(), time.Hour, 2)
+	history := testHistory()
+	for _, transaction := range history[:3] {
+		_ = storage.AppendHistory(defaultAccountID, transaction)
+	}
+
+	// the oldest transaction is evicted to keep the ceiling
+	expected := []string{history[2].Merchant, history[1].Merchant}
+	var result []string
+	retained, _ := storage.QueryHistory(defaultAccountID, history[0].Time.(time.Time).Add(-time.Hour), time.Time{})
+	for _, transaction := range retained {
+		result = append(result, transaction.Merchant)
+	}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("RetentionStorage.AppendHistory(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("RetentionStorage.AppendHistory(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
@@ -0,0 +1,149 @@
+package authorizer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var ErrReviewNotFound = errors.New("review not found")
+
+// ErrReviewExists is returned when queueing a review under the id of one still pending
+var ErrReviewExists = errors.New("review already pending")
+
+// Review is a transaction waiting for a manual decision, its amount being held on the account meanwhile
+type Review struct {
+	// ID of the operation when it came with one, otherwise the account and time of the transaction
+	ID          string      `json:"id"`
+	AccountID   string      `json:"account-id"`
+	Transaction Transaction `json:"transaction"`
+	Violations  []string    `json:"violations"`
+	// Reinitializations of the account when the review was queued: once the account is initialized again,
+	// its amount is no longer held
+	Reinitializations int `json:"reinitializations,omitempty"`
+}
+
+// reviewID identifies the review of a transaction operation: the id it came with, or its account and time,
+// as ids given from the line of the operation repeat from one input to the next
+func reviewID(operation TransactionOperation) string {
+	if operation.ID != "" && !operation.lineID {
+		return operation.ID
+	}
+	return fmt.Sprintf("%s@%s", accountID(operation), operation.Transaction.Time.(time.Time).Format(time.RFC3339Nano))
+}
+
+// sortReviews orders reviews as they were queued: by the time of their transaction, then by id
+func sortReviews(reviews []Review) {
+	sort.SliceStable(reviews, func(i, j int) bool {
+		a, b := reviews[i].Transaction.Time.(time.Time), reviews[j].Transaction.Time.(time.Time)
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return reviews[i].ID < reviews[j].ID
+	})
+}
+
+// queueReview holds the amount of a transaction sent to review on its account and queues it, returning
+// the id of the review. An operation that came with the id of a pending review is an operationError.
+func queueReview(operation TransactionOperation, output AccountOperationOutput, status AccountStatus, storage Storage) (string, error) {
+	id := accountID(operation)
+	review := Review{
+		ID:                reviewID(operation),
+		AccountID:         id,
+		Transaction:       operation.Transaction,
+		Violations:        output.Violations,
+		Reinitializations: status.reinitializations,
+	}
+	// an id the operation came with is refused while pending, a generated one is numbered instead
+	generated := operation.ID == "" || operation.lineID
+	for n := 2; ; n++ {
+		err := storage.PutReview(review)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, ErrReviewExists) {
+			return "", err
+		}
+		if !generated {
+			return "", operationError{err}
+		}
+		review.ID = fmt.Sprintf("%s#%d", reviewID(operation), n)
+	}
+
+	status.account.AvailableLimit = output.Account.AvailableLimit
+	status.held += operation.Transaction.Amount
+	return review.ID, storage.PutAccount(id, status)
+}
+
+// resolveReview releases the hold of a queued transaction: approved, the amount stays debited and the
+// transaction counts in the profile of the account, rejected, the amount is given back to the limit.
+// When the account was initialized again since it was queued, the new limit never held the amount,
+// so only the profile is updated.
+func resolveReview(storage Storage, rules Rules, id string, approve bool) (AccountStatus, error) {
+	review, ok, err := storage.GetReview(id)
+	if err != nil {
+		return AccountStatus{}, err
+	}
+	if !ok {
+		return AccountStatus{}, fmt.Errorf("%w: %s", ErrReviewNotFound, id)
+	}
+
+	status, err := storage.GetAccount(review.AccountID)
+	if err != nil {
+		return status, err
+	}
+	held := review.Reinitializations == status.reinitializations
+	if held {
+		status.held -= review.Transaction.Amount
+	}
+	if approve {
+		status.profile = status.profile.add(review.Transaction, rules.Anomaly.Window)
+	} else if held {
+		status.account.AvailableLimit += review.Transaction.Amount
+	}
+
+	if err := storage.PutAccount(review.AccountID, status); err != nil {
+		return status, err
+	}
+	return status, storage.DeleteReview(id)
+}
+
+// Reviews returns the transactions waiting for a manual decision, in the order they were queued
+func (authorizer *Authorizer) Reviews() ([]Review, error) {
+	return authorizer.storage.ListReviews()
+}
+
+// ApproveReview approves a queued transaction, returning the status of its account
+func (authorizer *Authorizer) ApproveReview(id string) (AccountStatus, error) {
+	return authorizer.resolveReview(id, DecisionApproved)
+}
+
+// RejectReview declines a queued transaction and gives its amount back, returning the status of its account
+func (authorizer *Authorizer) RejectReview(id string) (AccountStatus, error) {
+	return authorizer.resolveReview(id, DecisionDeclined)
+}
+
+func (authorizer *Authorizer) resolveReview(id string, decision string) (AccountStatus, error) {
+	authorizer.mutex.Lock()
+	defer authorizer.mutex.Unlock()
+
+	status, err := resolveReview(authorizer.storage, authorizer.rules, id, decision == DecisionApproved)
+	if err != nil {
+		return status, err
+	}
+
+	if authorizer.audit != nil {
+		input, err := json.Marshal(map[string]interface{}{"review": map[string]string{"id": id, "decision": decision}})
+		if err != nil {
+			return status, err
+		}
+		output := AccountOperationOutput{Account: status.account, Decision: decision, ID: id}
+		if err := authorizer.audit.Append(string(input), output); err != nil {
+			return status, err
+		}
+	}
+
+	return status, nil
+}
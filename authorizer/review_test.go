@@ -0,0 +1,244 @@
+package authorizer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testReviewQueue(t *testing.T, storage Storage) {
+	history := testHistory()
+	_ = storage.PutReview(Review{ID: "b", AccountID: defaultAccountID, Transaction: history[1]})
+	_ = storage.PutReview(Review{ID: "a", AccountID: defaultAccountID, Transaction: history[0]})
+	_ = storage.PutReview(Review{ID: "c", AccountID: "other", Transaction: history[2]})
+	_ = storage.DeleteReview("c")
+
+	// queued order is the order of the transactions
+	expected := []string{"a", "b"}
+	var result []string
+	reviews, err := storage.ListReviews()
+	for _, review := range reviews {
+		result = append(result, review.ID)
+	}
+	review, ok, _ := storage.GetReview("b")
+	// a pending review is not overwritten
+	putErr := storage.PutReview(Review{ID: "a", AccountID: defaultAccountID, Transaction: history[3]})
+
+	if err == nil && reflect.DeepEqual(expected, result) && ok && review.Transaction.Merchant == history[1].Merchant &&
+		errors.Is(putErr, ErrReviewExists) {
+		t.Logf("Storage.ListReviews() PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Storage.ListReviews() FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestMemoryStorageReviews(t *testing.T) {
+	testReviewQueue(t, NewMemoryStorage())
+}
+
+func TestFileStorageReviews(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.kv")
+	storage, err := OpenFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testReviewQueue(t, storage)
+	_ = storage.Close()
+
+	// the queue is still there once reopened
+	storage, err = OpenFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	expected := 2
+	reviews, err := storage.ListReviews()
+	result := len(reviews)
+
+	if err == nil && expected == result {
+		t.Logf("FileStorage.ListReviews() PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("FileStorage.ListReviews() FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+// newReviewAuthorizer sends doubled transactions to review, and queues two of them
+func newReviewAuthorizer(t *testing.T) *Authorizer {
+	rules := DefaultRules()
+	rules.Policy.Severities = map[string]string{DoubledTransaction: SeveritySoftDecline}
+	authorize := New(Options{Rules: &rules})
+
+	_, _ = authorize.Apply(Operation{Account: &Account{ActiveCard: true, AvailableLimit: 100}})
+	for i, id := range []string{"t-1", "t-2", "t-3"} {
+		output, err := authorize.Apply(Operation{ID: id, Transaction: &Transaction{
+			Merchant: "Burger King", Amount: 20, Time: "2019-02-13T11:00:00.000Z"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 && output.Decision != DecisionReview {
+			t.Fatalf("Authorizer.Apply(%s) decision %s", id, output.Decision)
+		}
+	}
+	return authorize
+}
+
+func TestAuthorizerReviewHold(t *testing.T) {
+	authorize := newReviewAuthorizer(t)
+
+	// both reviewed amounts are taken from the limit
+	expected := []interface{}{40, 40, []string{"t-2", "t-3"}}
+	status, _ := authorize.State("")
+	reviews, err := authorize.Reviews()
+	var ids []string
+	for _, review := range reviews {
+		ids = append(ids, review.ID)
+	}
+	result := []interface{}{status.Account().AvailableLimit, status.Held(), ids}
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("Authorizer.Reviews() PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Authorizer.Reviews() FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestAuthorizerResolveReview(t *testing.T) {
+	authorize := newReviewAuthorizer(t)
+
+	// approved, the amount stays debited; rejected, it is given back
+	expected := []interface{}{40, 20, 2, 60, 0, 2}
+	var result []interface{}
+	approved, err1 := authorize.ApproveReview("t-2")
+	result = append(result, approved.Account().AvailableLimit, approved.Held(), approved.Profile().Count)
+	rejected, err2 := authorize.RejectReview("t-3")
+	result = append(result, rejected.Account().AvailableLimit, rejected.Held(), rejected.Profile().Count)
+	_, err3 := authorize.ApproveReview("t-3")
+	reviews, _ := authorize.Reviews()
+
+	if err1 == nil && err2 == nil && errors.Is(err3, ErrReviewNotFound) && len(reviews) == 0 && reflect.DeepEqual(expected, result) {
+		t.Logf("Authorizer.ApproveReview(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Authorizer.ApproveReview(...) FAILED \nexpected: %v \nresult: %v %v %v %v", expected, result, err1, err2, err3)
+	}
+}
+
+func TestAuthorizerResolveReviewReinitialized(t *testing.T) {
+	authorize := newReviewAuthorizer(t)
+	_, _ = authorize.Apply(Operation{Account: &Account{ActiveCard: false, AvailableLimit: 500}})
+
+	// the new limit held nothing: rejected, nothing is given back, approved, only the profile counts it
+	expected := []interface{}{500, 0, 500, 0, 1, 500, 0, 2}
+	status, _ := authorize.State("")
+	result := []interface{}{status.Account().AvailableLimit, status.Held()}
+	rejected, err1 := authorize.RejectReview("t-2")
+	result = append(result, rejected.Account().AvailableLimit, rejected.Held(), rejected.Profile().Count)
+	approved, err2 := authorize.ApproveReview("t-3")
+	result = append(result, approved.Account().AvailableLimit, approved.Held(), approved.Profile().Count)
+
+	if err1 == nil && err2 == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("Authorizer.RejectReview(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Authorizer.RejectReview(...) FAILED \nexpected: %v \nresult: %v %v %v", expected, result, err1, err2)
+	}
+}
+
+func TestAuthorizerReviewGeneratedID(t *testing.T) {
+	rules := DefaultRules()
+	rules.Policy.Severities = map[string]string{DoubledTransaction: SeveritySoftDecline}
+	authorize := New(Options{Rules: &rules})
+
+	_, _ = authorize.Apply(Operation{Account: &Account{ActiveCard: true, AvailableLimit: 100}})
+	var errs []error
+	for i, id := range []string{"", "", "", "", "t-1", "t-1"} {
+		_, err := authorize.Apply(Operation{ID: id, Transaction: &Transaction{
+			Merchant: "Burger King", Amount: 10, Time: []string{"2019-02-13T11:00:00.000Z", "2019-02-13T11:10:00.000Z"}[i/3]}})
+		errs = append(errs, err)
+	}
+
+	// generated ids are numbered when they repeat, an id the operation came with is refused
+	expected := []string{"default@2019-02-13T11:00:00Z", "default@2019-02-13T11:00:00Z#2", "t-1"}
+	var result []string
+	reviews, err := authorize.Reviews()
+	for _, review := range reviews {
+		result = append(result, review.ID)
+	}
+
+	if err == nil && errs[4] == nil && errors.Is(errs[5], ErrReviewExists) && reflect.DeepEqual(expected, result) {
+		t.Logf("Authorizer.Reviews() PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Authorizer.Reviews() FAILED \nexpected: %v \nresult: %v %v %v", expected, result, err, errs)
+	}
+}
+
+func TestAuthorizerReviewBatches(t *testing.T) {
+	storage, err := OpenFileStorage(filepath.Join(t.TempDir(), "state.kv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	rules := DefaultRules()
+	rules.Policy.Severities = map[string]string{DoubledTransaction: SeveritySoftDecline}
+	batches := []string{`{"account": {"active-card": true, "available-limit": 100}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T11:00:00.000Z"}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T11:00:30.000Z"}}`,
+		`{"transaction": {"merchant": "Habbib's", "amount": 10, "time": "2019-02-13T12:00:00.000Z"}}
+{"transaction": {"merchant": "Habbib's", "amount": 10, "time": "2019-02-13T12:00:30.000Z"}}`}
+
+	for _, batch := range batches {
+		var writer bytes.Buffer
+		if err := New(Options{Storage: storage, Rules: &rules}).Run(context.Background(), strings.NewReader(batch), &writer); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the reviews of both batches are kept, though their operations were both given the id of line 3
+	expected := []string{"default@2019-02-13T11:00:30Z", "default@2019-02-13T12:00:30Z"}
+	var result []string
+	reviews, err := storage.ListReviews()
+	for _, review := range reviews {
+		result = append(result, review.ID)
+	}
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("FileStorage.ListReviews() PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("FileStorage.ListReviews() FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestAuthorizerRunReviewExists(t *testing.T) {
+	input := `{"account": {"active-card": true, "available-limit": 100}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}
+{"id": "x", "transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:30.000Z"}}
+{"transaction": {"merchant": "Habbib's", "amount": 10, "time": "2019-02-13T10:05:00.000Z"}}
+{"id": "x", "transaction": {"merchant": "Habbib's", "amount": 10, "time": "2019-02-13T10:05:30.000Z"}}
+{"transaction": {"merchant": "Subway", "amount": 10, "time": "2019-02-13T10:10:00.000Z"}}`
+
+	// the reused id is reported and leaves the state untouched, the next operation is still decided
+	expected := []string{`{"account":{"active-card":true,"available-limit":100},"violations":[],"decision":"approved","id":"1"}
+{"account":{"active-card":true,"available-limit":80},"violations":[],"decision":"approved","id":"2"}
+{"account":{"active-card":true,"available-limit":60},"violations":["doubled-transaction"],"decision":"review","review-id":"x","id":"x"}
+{"account":{"active-card":true,"available-limit":50},"violations":[],"decision":"approved","id":"4"}
+{"account":{"active-card":true,"available-limit":40},"violations":[],"decision":"approved","id":"6"}
+`, "line 5: review already pending: x\n"}
+
+	rules := DefaultRules()
+	rules.Policy.Severities = map[string]string{DoubledTransaction: SeveritySoftDecline}
+	for _, workers := range []int{1, 4} {
+		var writer, errs bytes.Buffer
+		err := New(Options{Rules: &rules, Workers: workers, Errors: &errs}).Run(context.Background(), strings.NewReader(input), &writer)
+		result := []string{writer.String(), errs.String()}
+
+		if err == nil && reflect.DeepEqual(expected, result) {
+			t.Logf("Authorizer.Run(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+		} else {
+			t.Errorf("Authorizer.Run(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+		}
+	}
+}
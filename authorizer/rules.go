@@ -0,0 +1,166 @@
+package authorizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	CardTestingSuspected = "card-testing-suspected"
+	StructuringSuspected = "structuring-suspected"
+)
+
+// Rules configures the rules that are not fixed by the business specification,
+// a rule is disabled while its configuration is zero
+type Rules struct {
+	CardTesting CardTestingRule `json:"card-testing"`
+	Structuring StructuringRule `json:"structuring"`
+	Anomaly     AnomalyRule     `json:"anomaly"`
+	FraudScore  FraudScoreRule  `json:"fraud-score"`
+	Policy      Policy          `json:"policy"`
+}
+
+// CardTestingRule flags a small transaction when the small transactions within the window,
+// itself included, were made at Merchants distinct merchants or more
+type CardTestingRule struct {
+	MaxAmount int      `json:"max-amount"`
+	Merchants int      `json:"merchants"`
+	Window    Duration `json:"window"`
+}
+
+// StructuringRule flags a transaction below Threshold when, with the transactions at the same merchant
+// within the window that are below Threshold too and were not declined, it adds up to more than Threshold
+type StructuringRule struct {
+	Threshold int      `json:"threshold"`
+	Window    Duration `json:"window"`
+}
+
+// DefaultRules leaves the fraud rules disabled, they are enabled through a rules file
+func DefaultRules() Rules {
+	return Rules{
+		// no model is shipped, the rule is enabled by setting one
+		FraudScore: FraudScoreRule{Threshold: 0.9},
+	}
+}
+
+// LoadRules reads a json rules file, the rules it leaves out keep their defaults
+func LoadRules(path string) (Rules, error) {
+	rules := DefaultRules()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rules, err
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return rules, fmt.Errorf("rules file %s not valid: %v", path, err)
+	}
+	if err := rules.Policy.validate(); err != nil {
+		return rules, fmt.Errorf("rules file %s not valid: %v", path, err)
+	}
+	if err := rules.FraudScore.load(filepath.Dir(path)); err != nil {
+		return rules, err
+	}
+	return rules, nil
+}
+
+// windows returns how far back in the history the configured rules look
+func (rules Rules) windows() []time.Duration {
+	var windows []time.Duration
+	if rules.CardTesting.enabled() {
+		windows = append(windows, time.Duration(rules.CardTesting.Window))
+	}
+	if rules.Structuring.enabled() {
+		windows = append(windows, time.Duration(rules.Structuring.Window))
+	}
+	return windows
+}
+
+func (rule CardTestingRule) enabled() bool {
+	return rule.MaxAmount > 0 && rule.Merchants > 0 && rule.Window > 0
+}
+
+func hasCardTesting(rule CardTestingRule, storage Storage, id string, transaction Transaction) (bool, error) {
+	merchants, err := cardTestingMerchants(rule, storage, id, transaction)
+	if err != nil {
+		return false, err
+	}
+	return rule.enabled() && merchants >= rule.Merchants, nil
+}
+
+// cardTestingMerchants counts the distinct merchants of the small transactions within the window of the
+// rule, the transaction included, zero when the rule is disabled or the transaction is not small
+func cardTestingMerchants(rule CardTestingRule, storage Storage, id string, transaction Transaction) (int, error) {
+	if !rule.enabled() || transaction.Amount > rule.MaxAmount {
+		return 0, nil
+	}
+
+	t1 := transaction.Time.(time.Time)
+	history, err := storage.QueryHistory(id, t1.Add(-time.Duration(rule.Window)), time.Time{})
+	if err != nil {
+		return 0, err
+	}
+
+	merchants := map[string]bool{transaction.Merchant: true}
+	for _, value := range history {
+		if value.Amount <= rule.MaxAmount {
+			merchants[value.Merchant] = true
+		}
+	}
+	return len(merchants), nil
+}
+
+func (rule StructuringRule) enabled() bool {
+	return rule.Threshold > 0 && rule.Window > 0
+}
+
+func hasStructuring(rule StructuringRule, storage Storage, id string, transaction Transaction) (bool, error) {
+	sum, err := structuringSum(rule, storage, id, transaction)
+	if err != nil {
+		return false, err
+	}
+	return sum > rule.Threshold, nil
+}
+
+// structuringSum adds up the transaction and the transactions at the same merchant within the window of
+// the rule that are below the threshold and were not declined, zero when the rule is disabled or the
+// transaction is not below the threshold
+func structuringSum(rule StructuringRule, storage Storage, id string, transaction Transaction) (int, error) {
+	if !rule.enabled() || transaction.Amount >= rule.Threshold {
+		return 0, nil
+	}
+
+	history, err := merchantHistory(storage, id, transaction, time.Duration(rule.Window))
+	if err != nil {
+		return 0, err
+	}
+
+	sum := transaction.Amount
+	for _, value := range history {
+		if value.Amount < rule.Threshold && !value.declined {
+			sum += value.Amount
+		}
+	}
+	return sum, nil
+}
+
+// Duration is a time.Duration written as a string such as "10m" in json
+type Duration time.Duration
+
+func (duration Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(duration).String())
+}
+
+func (duration *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string such as \"10m\": %v", err)
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*duration = Duration(parsed)
+	return nil
+}
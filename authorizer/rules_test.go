@@ -0,0 +1,240 @@
+package authorizer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testCardTestingStorage() Storage {
+	storage := NewMemoryStorage()
+	for i, merchant := range []string{"Shop A", "Shop B", "Shop B", "Shop C"} {
+		_ = storage.AppendHistory(defaultAccountID, Transaction{
+			Merchant: merchant,
+			Amount:   []int{1, 2, 50, 90}[i],
+			Time:     time.Date(2019, 2, 13, 10, i, 0, 0, time.UTC),
+		})
+	}
+	return storage
+}
+
+// testCardTestingRule is the card-testing rule of the tests, the rule being disabled by default
+var testCardTestingRule = CardTestingRule{MaxAmount: 5, Merchants: 3, Window: Duration(10 * time.Minute)}
+
+func TestHasCardTesting(t *testing.T) {
+	rule := testCardTestingRule
+	at := time.Date(2019, 2, 13, 10, 5, 0, 0, time.UTC)
+	transactions := []Transaction{
+		// small, third distinct merchant of small transactions
+		{Merchant: "Shop D", Amount: 1, Time: at},
+		// small, but only two distinct merchants
+		{Merchant: "Shop A", Amount: 1, Time: at},
+		// not small
+		{Merchant: "Shop D", Amount: 6, Time: at},
+		// small transactions are out of the window
+		{Merchant: "Shop D", Amount: 1, Time: at.Add(10 * time.Minute)},
+	}
+
+	expected := []bool{true, false, false, false}
+	var result []bool
+	for _, transaction := range transactions {
+		suspected, err := hasCardTesting(rule, testCardTestingStorage(), defaultAccountID, transaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, suspected)
+	}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("hasCardTesting(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("hasCardTesting(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestHasCardTestingDisabled(t *testing.T) {
+	transaction := Transaction{Merchant: "Shop D", Amount: 1, Time: time.Date(2019, 2, 13, 10, 5, 0, 0, time.UTC)}
+
+	result, err := hasCardTesting(CardTestingRule{}, testCardTestingStorage(), defaultAccountID, transaction)
+
+	if err == nil && !result {
+		t.Logf("hasCardTesting(...) PASSED \nexpected: %v \nresult: %v", false, result)
+	} else {
+		t.Errorf("hasCardTesting(...) FAILED \nexpected: %v \nresult: %v %v", false, result, err)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	_ = os.WriteFile(path, []byte(`{"card-testing": {"max-amount": 10, "window": "1h"}}`), 0o644)
+
+	expected := DefaultRules()
+	expected.CardTesting.MaxAmount = 10
+	expected.CardTesting.Window = Duration(time.Hour)
+
+	result, err := LoadRules(path)
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("LoadRules(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("LoadRules(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestLoadRulesNotValid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	_ = os.WriteFile(path, []byte(`{"card-testing": {"window": 10}}`), 0o644)
+
+	_, err := LoadRules(path)
+
+	if err != nil {
+		t.Logf("LoadRules(...) PASSED \nexpected: error \nresult: %v", err)
+	} else {
+		t.Errorf("LoadRules(...) FAILED \nexpected: error \nresult: %v", err)
+	}
+}
+
+func testStructuringStorage() Storage {
+	storage := NewMemoryStorage()
+	for i, merchant := range []string{"Shop A", "Shop A", "Shop B", "Shop A"} {
+		_ = storage.AppendHistory(defaultAccountID, Transaction{
+			Merchant: merchant,
+			Amount:   []int{200, 250, 400, 600}[i],
+			Time:     time.Date(2019, 2, 13, 10, i*10, 0, 0, time.UTC),
+		})
+	}
+	return storage
+}
+
+// testStructuringRule is the structuring rule of the tests, the rule being disabled by default
+var testStructuringRule = StructuringRule{Threshold: 500, Window: Duration(30 * time.Minute)}
+
+func TestHasStructuring(t *testing.T) {
+	rule := testStructuringRule
+	at := time.Date(2019, 2, 13, 10, 29, 0, 0, time.UTC)
+	transactions := []Transaction{
+		// below the threshold, adds up to 501 with the parts at the same merchant
+		{Merchant: "Shop A", Amount: 51, Time: at},
+		// adds up to exactly the threshold
+		{Merchant: "Shop A", Amount: 50, Time: at},
+		// not below the threshold
+		{Merchant: "Shop A", Amount: 500, Time: at},
+		// the parts are at another merchant
+		{Merchant: "Shop C", Amount: 499, Time: at},
+		// the first part is out of the window
+		{Merchant: "Shop A", Amount: 100, Time: at.Add(5 * time.Minute)},
+	}
+
+	expected := []bool{true, false, false, false, false}
+	var result []bool
+	for _, transaction := range transactions {
+		suspected, err := hasStructuring(rule, testStructuringStorage(), defaultAccountID, transaction)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, suspected)
+	}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("hasStructuring(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("hasStructuring(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestHasStructuringDisabled(t *testing.T) {
+	transaction := Transaction{Merchant: "Shop A", Amount: 100, Time: time.Date(2019, 2, 13, 10, 29, 0, 0, time.UTC)}
+
+	result, err := hasStructuring(StructuringRule{}, testStructuringStorage(), defaultAccountID, transaction)
+
+	if err == nil && !result {
+		t.Logf("hasStructuring(...) PASSED \nexpected: %v \nresult: %v", false, result)
+	} else {
+		t.Errorf("hasStructuring(...) FAILED \nexpected: %v \nresult: %v %v", false, result, err)
+	}
+}
+
+func TestHasStructuringDeclined(t *testing.T) {
+	storage := NewMemoryStorage()
+	at := time.Date(2019, 2, 13, 10, 0, 0, 0, time.UTC)
+	_ = storage.AppendHistory(defaultAccountID, Transaction{Merchant: "Shop A", Amount: 300, Time: at})
+	_ = storage.AppendHistory(defaultAccountID, Transaction{Merchant: "Shop A", Amount: 300, Time: at.Add(time.Minute), declined: true})
+
+	// the declined part is not summed: 300 + 150 is below the threshold
+	expected := false
+	result, err := hasStructuring(testStructuringRule, storage, defaultAccountID,
+		Transaction{Merchant: "Shop A", Amount: 150, Time: at.Add(2 * time.Minute)})
+
+	if err == nil && expected == result {
+		t.Logf("hasStructuring(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("hasStructuring(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestDefaultRetentionRules(t *testing.T) {
+	// the windows of the rules, card-testing widening them once enabled
+	expected := []time.Duration{2 * time.Minute, 10 * time.Minute}
+
+	rules := DefaultRules()
+	result := []time.Duration{defaultRetention(rules)}
+	rules.CardTesting = testCardTestingRule
+	result = append(result, defaultRetention(rules))
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("defaultRetention(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("defaultRetention(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestAuthorizerRunCardTesting(t *testing.T) {
+	input := `{"account": {"active-card": true, "available-limit": 1000}}
+{"transaction": {"merchant": "Shop A", "amount": 1, "time": "2019-02-13T10:00:00.000Z"}}
+{"transaction": {"merchant": "Shop B", "amount": 2, "time": "2019-02-13T10:03:00.000Z"}}
+{"transaction": {"merchant": "Shop C", "amount": 1, "time": "2019-02-13T10:06:00.000Z"}}`
+
+	expected := `{"account":{"active-card":true,"available-limit":1000},"violations":[],"decision":"approved","id":"1"}
+{"account":{"active-card":true,"available-limit":999},"violations":[],"decision":"approved","id":"2"}
+{"account":{"active-card":true,"available-limit":997},"violations":[],"decision":"approved","id":"3"}
+{"account":{"active-card":true,"available-limit":997},"violations":["card-testing-suspected"],"decision":"declined","id":"4"}
+`
+	rules := DefaultRules()
+	rules.CardTesting = testCardTestingRule
+	var writer bytes.Buffer
+	err := New(Options{Rules: &rules}).Run(context.Background(), strings.NewReader(input), &writer)
+	result := writer.String()
+
+	if err == nil && expected == result {
+		t.Logf("Authorizer.Run(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Authorizer.Run(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestAuthorizerRunStructuring(t *testing.T) {
+	input := `{"account": {"active-card": true, "available-limit": 1000}}
+{"transaction": {"merchant": "Shop A", "amount": 300, "time": "2019-02-13T10:00:00.000Z"}}
+{"transaction": {"merchant": "Shop A", "amount": 300, "time": "2019-02-13T10:05:00.000Z"}}`
+
+	expected := `{"account":{"active-card":true,"available-limit":1000},"violations":[],"decision":"approved","id":"1"}
+{"account":{"active-card":true,"available-limit":700},"violations":[],"decision":"approved","id":"2"}
+{"account":{"active-card":true,"available-limit":700},"violations":["structuring-suspected"],"decision":"declined","id":"3"}
+`
+	rules := DefaultRules()
+	rules.Structuring = testStructuringRule
+	var writer bytes.Buffer
+	err := New(Options{Rules: &rules}).Run(context.Background(), strings.NewReader(input), &writer)
+	result := writer.String()
+
+	if err == nil && expected == result {
+		t.Logf("Authorizer.Run(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Authorizer.Run(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
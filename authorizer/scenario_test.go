@@ -0,0 +1,178 @@
+package authorizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "write the outputs of the scenarios as their expected outputs")
+
+// scenarioDir holds one directory per scenario, see its README
+const scenarioDir = "testdata/scenarios"
+
+// scenarioOptions are read from the optional options.json of a scenario
+type scenarioOptions struct {
+	InputFormat  string `json:"input-format"`
+	OutputFormat string `json:"output-format"`
+	Workers      int    `json:"workers"`
+}
+
+// TestScenarios runs the input of every scenario through Run and compares the outputs and the errors
+// with the expected ones, which -update writes instead
+func TestScenarios(t *testing.T) {
+	entries, err := os.ReadDir(scenarioDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(scenarioDir, entry.Name())
+		t.Run(entry.Name(), func(t *testing.T) {
+			output, errs, err := runScenario(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, filepath.Join(dir, "output"), output)
+			checkGolden(t, filepath.Join(dir, "errors"), errs)
+		})
+	}
+}
+
+func runScenario(dir string) (string, string, error) {
+	var options scenarioOptions
+	if data, err := os.ReadFile(filepath.Join(dir, "options.json")); err == nil {
+		if err := json.Unmarshal(data, &options); err != nil {
+			return "", "", fmt.Errorf("options.json not valid: %v", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return "", "", err
+	}
+
+	rules := DefaultRules()
+	if path := filepath.Join(dir, "rules.json"); fileExists(path) {
+		var err error
+		if rules, err = LoadRules(path); err != nil {
+			return "", "", err
+		}
+	}
+
+	input, err := os.ReadFile(filepath.Join(dir, "input"))
+	if err != nil {
+		return "", "", err
+	}
+
+	var output, errs bytes.Buffer
+	err = New(Options{
+		Rules:        &rules,
+		Workers:      options.Workers,
+		InputFormat:  options.InputFormat,
+		OutputFormat: options.OutputFormat,
+		Errors:       &errs,
+	}).Run(context.Background(), bytes.NewReader(input), &output)
+	return output.String(), errs.String(), err
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// checkGolden compares a result with the content of a golden file, a missing file standing for no content
+func checkGolden(t *testing.T, path string, result string) {
+	t.Helper()
+	if *update {
+		if result == "" {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				t.Fatal(err)
+			}
+			return
+		}
+		if err := os.WriteFile(path, []byte(result), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		t.Fatal(err)
+	}
+	expected := string(data)
+
+	if expected == result {
+		t.Logf("%s PASSED", path)
+	} else {
+		t.Errorf("%s FAILED, run go test ./authorizer -run TestScenarios -update to accept the result \n%s",
+			path, lineDiff(expected, result))
+	}
+}
+
+// lineDiff lists the lines of expected missing from result with -, and the lines added with +
+func lineDiff(expected string, result string) string {
+	a, b := splitLines(expected), splitLines(result)
+
+	// longest common subsequence of the lines, lengths from the end
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	var diff strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&diff, "  %s\n", a[i])
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lengths[i+1][j] >= lengths[i][j+1]):
+			fmt.Fprintf(&diff, "- %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&diff, "+ %s\n", b[j])
+			j++
+		}
+	}
+	return diff.String()
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+func TestLineDiff(t *testing.T) {
+	expected := "  a\n- b\n+ x\n  c\n+ d\n"
+
+	result := lineDiff("a\nb\nc\n", "a\nx\nc\nd\n")
+
+	if expected == result {
+		t.Logf("lineDiff(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("lineDiff(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
@@ -0,0 +1,157 @@
+package authorizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const HighFraudScore = "high-fraud-score"
+
+// sinceLastCap is the time since the last transaction given when there is none that recent in history
+const sinceLastCap = 10 * time.Minute
+
+// Features describes a transaction against the state of its account, as scored by a Model
+type Features struct {
+	// AmountRatio is the amount over the available limit before the transaction
+	AmountRatio float64 `json:"amount-ratio"`
+	// SecondsSinceLast is the time since the last transaction in history, at most 10 minutes
+	SecondsSinceLast float64 `json:"seconds-since-last"`
+	// NewMerchant is 1 when the account has no approved transaction at the merchant, 0 otherwise
+	NewMerchant float64 `json:"new-merchant"`
+	// RecentTransactions is the count of transactions within the high-frequency-small-interval window
+	RecentTransactions float64 `json:"recent-transactions"`
+	// RecentMerchantTransactions is the count of transactions at the merchant within the doubled-transaction window
+	RecentMerchantTransactions float64 `json:"recent-merchant-transactions"`
+}
+
+// FeatureNames are the names of the features, in the order of Features.Values
+var FeatureNames = []string{"amount-ratio", "seconds-since-last", "new-merchant", "recent-transactions", "recent-merchant-transactions"}
+
+func (features Features) Values() []float64 {
+	return []float64{features.AmountRatio, features.SecondsSinceLast, features.NewMerchant,
+		features.RecentTransactions, features.RecentMerchantTransactions}
+}
+
+// transactionFeatures computes the features of a transaction from the status and history of its account,
+// before the transaction is applied
+func transactionFeatures(status AccountStatus, storage Storage, id string, transaction Transaction) (Features, error) {
+	var features Features
+	features.AmountRatio = float64(transaction.Amount) / math.Max(float64(status.account.AvailableLimit), 1)
+
+	recent, err := recentHistory(storage, id, transaction, sinceLastCap)
+	if err != nil {
+		return features, err
+	}
+	sinceLast := sinceLastCap
+	if len(recent) > 0 {
+		sinceLast = transaction.Time.(time.Time).Sub(recent[len(recent)-1].Time.(time.Time))
+	}
+	features.SecondsSinceLast = sinceLast.Seconds()
+
+	if status.profile.Merchants[transaction.Merchant] == 0 {
+		features.NewMerchant = 1
+	}
+
+	frequent, err := recentHistory(storage, id, transaction, highFrequencyWindow)
+	if err != nil {
+		return features, err
+	}
+	features.RecentTransactions = float64(len(frequent))
+
+	doubled, err := merchantHistory(storage, id, transaction, doubledTransactionWindow)
+	if err != nil {
+		return features, err
+	}
+	features.RecentMerchantTransactions = float64(len(doubled))
+
+	return features, nil
+}
+
+// Model is a logistic regression over the features of a transaction
+type Model struct {
+	Bias    float64            `json:"bias"`
+	Weights map[string]float64 `json:"weights"` // by feature name, a feature without weight is ignored
+}
+
+// LoadModel reads a json model file, rejecting weights of unknown features
+func LoadModel(path string) (Model, error) {
+	var model Model
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return model, err
+	}
+	if err := json.Unmarshal(data, &model); err != nil {
+		return model, fmt.Errorf("model file %s not valid: %v", path, err)
+	}
+
+	known := map[string]bool{}
+	for _, name := range FeatureNames {
+		known[name] = true
+	}
+	for name := range model.Weights {
+		if !known[name] {
+			return model, fmt.Errorf("model file %s not valid: unknown feature %s", path, name)
+		}
+	}
+	return model, nil
+}
+
+// Score returns the probability of fraud given the features, rounded to 4 decimals
+func (model Model) Score(features Features) float64 {
+	z := model.Bias
+	for i, value := range features.Values() {
+		z += model.Weights[FeatureNames[i]] * value
+	}
+	return math.Round(10000/(1+math.Exp(-z))) / 10000
+}
+
+// FraudScoreRule scores every transaction with the model file at Model, a path relative to the rules file,
+// flagging it when the score reaches Threshold
+type FraudScoreRule struct {
+	Model     string  `json:"model"`
+	Threshold float64 `json:"threshold"`
+	model     *Model
+}
+
+func (rule FraudScoreRule) enabled() bool {
+	return rule.model != nil && rule.Threshold > 0
+}
+
+// WithModel returns the rule scoring with the given model
+func (rule FraudScoreRule) WithModel(model Model) FraudScoreRule {
+	rule.model = &model
+	return rule
+}
+
+// load reads the model of the rule, relative to dir
+func (rule *FraudScoreRule) load(dir string) error {
+	if rule.Model == "" {
+		return nil
+	}
+	path := rule.Model
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	model, err := LoadModel(path)
+	if err != nil {
+		return err
+	}
+	rule.model = &model
+	return nil
+}
+
+// fraudScore returns the score of a transaction and whether it was scored at all
+func fraudScore(rule FraudScoreRule, status AccountStatus, storage Storage, id string, transaction Transaction) (float64, bool, error) {
+	if !rule.enabled() {
+		return 0, false, nil
+	}
+	features, err := transactionFeatures(status, storage, id, transaction)
+	if err != nil {
+		return 0, false, err
+	}
+	return rule.model.Score(features), true, nil
+}
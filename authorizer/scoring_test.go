@@ -0,0 +1,132 @@
+package authorizer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransactionFeatures(t *testing.T) {
+	storage := NewMemoryStorage()
+	for i, merchant := range []string{"Shop A", "Shop B", "Shop A"} {
+		_ = storage.AppendHistory(defaultAccountID, Transaction{
+			Merchant: merchant,
+			Amount:   10,
+			Time:     time.Date(2019, 2, 13, 10, i, 0, 0, time.UTC),
+		})
+	}
+	status := AccountStatus{account: Account{ActiveCard: true, AvailableLimit: 200}, hasAccount: true,
+		profile: Profile{Count: 1, Merchants: map[string]int{"Shop B": 1}}}
+	transaction := Transaction{Merchant: "Shop A", Amount: 50, Time: time.Date(2019, 2, 13, 10, 2, 30, 0, time.UTC)}
+
+	expected := Features{
+		AmountRatio:                0.25,
+		SecondsSinceLast:           30,
+		NewMerchant:                1,
+		RecentTransactions:         2,
+		RecentMerchantTransactions: 1,
+	}
+	result, err := transactionFeatures(status, storage, defaultAccountID, transaction)
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("transactionFeatures(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("transactionFeatures(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestTransactionFeaturesNoHistory(t *testing.T) {
+	status := AccountStatus{account: Account{ActiveCard: true, AvailableLimit: 0}, hasAccount: true}
+	transaction := Transaction{Merchant: "Shop A", Amount: 50, Time: time.Date(2019, 2, 13, 10, 0, 0, 0, time.UTC)}
+
+	expected := Features{AmountRatio: 50, SecondsSinceLast: 600, NewMerchant: 1}
+	result, err := transactionFeatures(status, NewMemoryStorage(), defaultAccountID, transaction)
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("transactionFeatures(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("transactionFeatures(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestModelScore(t *testing.T) {
+	model := Model{Bias: -2, Weights: map[string]float64{"amount-ratio": 4, "new-merchant": 1}}
+	features := []Features{
+		{AmountRatio: 0.5},
+		{AmountRatio: 0.5, NewMerchant: 1, SecondsSinceLast: 600},
+		{},
+	}
+
+	expected := []float64{0.5, 0.7311, 0.1192}
+	var result []float64
+	for _, value := range features {
+		result = append(result, model.Score(value))
+	}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("Model.Score(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Model.Score(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestLoadModelUnknownFeature(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.json")
+	_ = os.WriteFile(path, []byte(`{"bias": 1, "weights": {"amount": 2}}`), 0o644)
+
+	_, err := LoadModel(path)
+
+	if err != nil {
+		t.Logf("LoadModel(...) PASSED \nexpected: error \nresult: %v", err)
+	} else {
+		t.Errorf("LoadModel(...) FAILED \nexpected: error \nresult: %v", err)
+	}
+}
+
+func TestLoadRulesModel(t *testing.T) {
+	dir := t.TempDir()
+	_ = os.WriteFile(filepath.Join(dir, "model.json"), []byte(`{"bias": -1, "weights": {"new-merchant": 2}}`), 0o644)
+	path := filepath.Join(dir, "rules.json")
+	_ = os.WriteFile(path, []byte(`{"fraud-score": {"model": "model.json", "threshold": 0.5}}`), 0o644)
+
+	expected := DefaultRules()
+	expected.FraudScore = FraudScoreRule{Model: "model.json", Threshold: 0.5}.
+		WithModel(Model{Bias: -1, Weights: map[string]float64{"new-merchant": 2}})
+
+	result, err := LoadRules(path)
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("LoadRules(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("LoadRules(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestAuthorizerRunFraudScore(t *testing.T) {
+	input := `{"account": {"active-card": true, "available-limit": 100}}
+{"transaction": {"merchant": "Shop A", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}
+{"transaction": {"merchant": "Shop A", "amount": 10, "time": "2019-02-13T11:00:00.000Z"}}
+{"transaction": {"merchant": "Shop B", "amount": 70, "time": "2019-02-13T12:00:00.000Z"}}`
+
+	expected := `{"account":{"active-card":true,"available-limit":100},"violations":[],"decision":"approved","id":"1"}
+{"account":{"active-card":true,"available-limit":80},"violations":[],"decision":"approved","fraud-score":0.4502,"id":"2"}
+{"account":{"active-card":true,"available-limit":70},"violations":[],"decision":"approved","fraud-score":0.0759,"id":"3"}
+{"account":{"active-card":true,"available-limit":70},"violations":["high-fraud-score"],"decision":"declined","fraud-score":0.9526,"id":"4"}
+`
+	rules := DefaultRules()
+	rules.FraudScore = rules.FraudScore.WithModel(Model{Bias: -3, Weights: map[string]float64{"amount-ratio": 4, "new-merchant": 2}})
+	var writer bytes.Buffer
+	err := New(Options{Rules: &rules}).Run(context.Background(), strings.NewReader(input), &writer)
+	result := writer.String()
+
+	if err == nil && expected == result {
+		t.Logf("Authorizer.Run(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Authorizer.Run(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
@@ -0,0 +1,464 @@
+package authorizer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultAccountID = "default"
+
+// Storage abstracts the account state and the transaction history used by the rules,
+// implementations must be safe for concurrent use
+type Storage interface {
+	GetAccount(id string) (AccountStatus, error)
+	PutAccount(id string, status AccountStatus) error
+	AppendHistory(id string, transaction Transaction) error
+	// QueryHistory returns the transactions (ordered by time) after from and up to to, a zero to means no upper bound
+	QueryHistory(id string, from time.Time, to time.Time) ([]Transaction, error)
+	// EvictHistory removes the transactions up to before and all but the newest keep ones (0 keeps all),
+	// returning how many were removed
+	EvictHistory(id string, before time.Time, keep int) (int, error)
+	// HistorySize returns how many transactions are kept in history across all accounts
+	HistorySize() int
+	// PutReview queues a transaction for review, an ErrReviewExists when one with the same id is pending
+	PutReview(review Review) error
+	// GetReview returns the queued review of an id, ok being false when there is none
+	GetReview(id string) (review Review, ok bool, err error)
+	DeleteReview(id string) error
+	// ListReviews returns the queued reviews, in the order they were queued
+	ListReviews() ([]Review, error)
+}
+
+type accountStatusJSON struct {
+	Account    Account  `json:"account"`
+	HasAccount bool     `json:"has-account"`
+	Initial    Account  `json:"initial-account"`
+	Profile    *Profile `json:"profile,omitempty"`
+	Held       int      `json:"held,omitempty"`
+	// times the account was initialized again, see AccountStatus
+	Reinitializations int `json:"reinitializations,omitempty"`
+}
+
+// historyJSON is a transaction of the history as it is stored, with its decision
+type historyJSON struct {
+	Transaction
+	Declined bool `json:"declined,omitempty"`
+}
+
+func (status AccountStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(accountStatusJSON{
+		Account:           status.account,
+		HasAccount:        status.hasAccount,
+		Initial:           status.initial,
+		Profile:           profileJSON(status.profile),
+		Held:              status.held,
+		Reinitializations: status.reinitializations,
+	})
+}
+
+// profileJSON leaves out the profile of an account without approved transactions
+func profileJSON(profile Profile) *Profile {
+	if profile.Count == 0 {
+		return nil
+	}
+	return &profile
+}
+
+func (status *AccountStatus) UnmarshalJSON(data []byte) error {
+	var value accountStatusJSON
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	status.account = value.Account
+	status.hasAccount = value.HasAccount
+	status.initial = value.Initial
+	status.profile = Profile{}
+	if value.Profile != nil {
+		status.profile = *value.Profile
+	}
+	status.held = value.Held
+	status.reinitializations = value.Reinitializations
+	return nil
+}
+
+func inHistoryRange(value time.Time, from time.Time, to time.Time) bool {
+	return value.After(from) && (to.IsZero() || !value.After(to))
+}
+
+// MemoryStorage keeps every account and its history (ordered by time) in memory
+type MemoryStorage struct {
+	mutex    sync.RWMutex
+	accounts map[string]AccountStatus
+	history  map[string][]Transaction
+	size     int
+	reviews  map[string]Review
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		accounts: map[string]AccountStatus{},
+		history:  map[string][]Transaction{},
+		reviews:  map[string]Review{},
+	}
+}
+
+func (storage *MemoryStorage) GetAccount(id string) (AccountStatus, error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+	return storage.accounts[id], nil
+}
+
+func (storage *MemoryStorage) PutAccount(id string, status AccountStatus) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	storage.accounts[id] = status
+	return nil
+}
+
+func (storage *MemoryStorage) AppendHistory(id string, transaction Transaction) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	history := storage.history[id]
+	value := transaction.Time.(time.Time)
+
+	// keep insertion order between transactions at the same time
+	i := sort.Search(len(history), func(i int) bool {
+		return history[i].Time.(time.Time).After(value)
+	})
+	history = append(history, Transaction{})
+	copy(history[i+1:], history[i:])
+	history[i] = transaction
+
+	storage.history[id] = history
+	storage.size++
+	return nil
+}
+
+func (storage *MemoryStorage) QueryHistory(id string, from time.Time, to time.Time) ([]Transaction, error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+
+	var history []Transaction
+	for _, transaction := range storage.history[id] {
+		if inHistoryRange(transaction.Time.(time.Time), from, to) {
+			history = append(history, transaction)
+		}
+	}
+	return history, nil
+}
+
+func (storage *MemoryStorage) EvictHistory(id string, before time.Time, keep int) (int, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	history := storage.history[id]
+
+	count := sort.Search(len(history), func(i int) bool {
+		return history[i].Time.(time.Time).After(before)
+	})
+	if keep > 0 && len(history)-count > keep {
+		count = len(history) - keep
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	// copy the retained transactions so the evicted ones can be released
+	retained := make([]Transaction, len(history)-count)
+	copy(retained, history[count:])
+	if len(retained) == 0 {
+		delete(storage.history, id)
+	} else {
+		storage.history[id] = retained
+	}
+	storage.size -= count
+
+	return count, nil
+}
+
+func (storage *MemoryStorage) HistorySize() int {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+	return storage.size
+}
+
+func (storage *MemoryStorage) PutReview(review Review) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	if _, ok := storage.reviews[review.ID]; ok {
+		return fmt.Errorf("%w: %s", ErrReviewExists, review.ID)
+	}
+	storage.reviews[review.ID] = review
+	return nil
+}
+
+func (storage *MemoryStorage) GetReview(id string) (Review, bool, error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+
+	review, ok := storage.reviews[id]
+	return review, ok, nil
+}
+
+func (storage *MemoryStorage) DeleteReview(id string) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	delete(storage.reviews, id)
+	return nil
+}
+
+func (storage *MemoryStorage) ListReviews() ([]Review, error) {
+	storage.mutex.RLock()
+	defer storage.mutex.RUnlock()
+
+	var reviews []Review
+	for _, review := range storage.reviews {
+		reviews = append(reviews, review)
+	}
+	sortReviews(reviews)
+	return reviews, nil
+}
+
+// FileStorage persists every account and its history in an embedded KV file
+type FileStorage struct {
+	mutex    sync.Mutex
+	kv       *KV
+	sequence uint64
+	size     int
+}
+
+func OpenFileStorage(path string) (*FileStorage, error) {
+	kv, err := OpenKV(path)
+	if err != nil {
+		return nil, err
+	}
+
+	storage := &FileStorage{kv: kv}
+
+	// restore the history sequence from the last stored transaction
+	for _, key := range kv.Keys("history\x00", "history\x01") {
+		storage.size++
+		parts := strings.Split(key, "\x00")
+		sequence, err := strconv.ParseUint(parts[len(parts)-1], 10, 64)
+		if err == nil && sequence > storage.sequence {
+			storage.sequence = sequence
+		}
+	}
+
+	return storage, nil
+}
+
+func accountKey(id string) string {
+	return "account\x00" + id
+}
+
+func reviewKey(id string) string {
+	return "review\x00" + id
+}
+
+func historyPrefix(id string) string {
+	return "history\x00" + id + "\x00"
+}
+
+// historyTime keeps lexical order of keys equal to chronological order, even before 1970
+func historyTime(value time.Time) string {
+	return fmt.Sprintf("%020d", uint64(value.UnixNano())^(1<<63))
+}
+
+func (storage *FileStorage) GetAccount(id string) (AccountStatus, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	var status AccountStatus
+
+	value, ok, err := storage.kv.Get(accountKey(id))
+	if err != nil || !ok {
+		return status, err
+	}
+
+	err = json.Unmarshal(value, &status)
+	return status, err
+}
+
+func (storage *FileStorage) PutAccount(id string, status AccountStatus) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	value, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	return storage.kv.Put(accountKey(id), value)
+}
+
+func (storage *FileStorage) AppendHistory(id string, transaction Transaction) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	value, err := json.Marshal(historyJSON{Transaction: transaction, Declined: transaction.declined})
+	if err != nil {
+		return err
+	}
+
+	storage.sequence++
+	key := fmt.Sprintf("%s%s\x00%020d", historyPrefix(id), historyTime(transaction.Time.(time.Time)), storage.sequence)
+
+	if err := storage.kv.Put(key, value); err != nil {
+		return err
+	}
+	storage.size++
+	return nil
+}
+
+func (storage *FileStorage) QueryHistory(id string, from time.Time, to time.Time) ([]Transaction, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	var history []Transaction
+
+	prefix := historyPrefix(id)
+	end := prefix + "\xff"
+	if !to.IsZero() {
+		end = prefix + historyTime(to) + "\xff"
+	}
+
+	err := storage.kv.Scan(prefix+historyTime(from), end, func(key string, value []byte) error {
+		var stored historyJSON
+		if err := json.Unmarshal(value, &stored); err != nil {
+			return err
+		}
+		transaction := stored.Transaction
+		transaction.declined = stored.Declined
+
+		parsed, err := parseStoredTime(&transaction)
+		if err != nil {
+			return err
+		}
+
+		if inHistoryRange(parsed, from, to) {
+			history = append(history, transaction)
+		}
+		return nil
+	})
+
+	return history, err
+}
+
+func (storage *FileStorage) EvictHistory(id string, before time.Time, keep int) (int, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	prefix := historyPrefix(id)
+	keys := storage.kv.Keys(prefix, prefix+"\xff")
+
+	count := sort.Search(len(keys), func(i int) bool {
+		return keys[i] > prefix+historyTime(before)+"\xff"
+	})
+	if keep > 0 && len(keys)-count > keep {
+		count = len(keys) - keep
+	}
+
+	for _, key := range keys[:count] {
+		if err := storage.kv.Delete(key); err != nil {
+			return 0, err
+		}
+		storage.size--
+	}
+
+	return count, nil
+}
+
+func (storage *FileStorage) HistorySize() int {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	return storage.size
+}
+
+// parseStoredTime parses back the time of a transaction read from the file
+func parseStoredTime(transaction *Transaction) (time.Time, error) {
+	data, _ := transaction.Time.(string)
+	parsed, err := parseTime(data, nil)
+	if err != nil {
+		return parsed, err
+	}
+	transaction.Time = parsed
+	return parsed, nil
+}
+
+func (storage *FileStorage) PutReview(review Review) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	_, ok, err := storage.kv.Get(reviewKey(review.ID))
+	if err != nil {
+		return err
+	}
+	if ok {
+		return fmt.Errorf("%w: %s", ErrReviewExists, review.ID)
+	}
+
+	value, err := json.Marshal(review)
+	if err != nil {
+		return err
+	}
+	return storage.kv.Put(reviewKey(review.ID), value)
+}
+
+func (storage *FileStorage) GetReview(id string) (Review, bool, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	var review Review
+
+	value, ok, err := storage.kv.Get(reviewKey(id))
+	if err != nil || !ok {
+		return review, false, err
+	}
+
+	if err := json.Unmarshal(value, &review); err != nil {
+		return review, false, err
+	}
+	_, err = parseStoredTime(&review.Transaction)
+	return review, err == nil, err
+}
+
+func (storage *FileStorage) DeleteReview(id string) error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	return storage.kv.Delete(reviewKey(id))
+}
+
+func (storage *FileStorage) ListReviews() ([]Review, error) {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+
+	var reviews []Review
+	err := storage.kv.Scan("review\x00", "review\x01", func(key string, value []byte) error {
+		var review Review
+		if err := json.Unmarshal(value, &review); err != nil {
+			return err
+		}
+		if _, err := parseStoredTime(&review.Transaction); err != nil {
+			return err
+		}
+		reviews = append(reviews, review)
+		return nil
+	})
+	sortReviews(reviews)
+	return reviews, err
+}
+
+func (storage *FileStorage) Close() error {
+	storage.mutex.Lock()
+	defer storage.mutex.Unlock()
+	return storage.kv.Close()
+}
@@ -0,0 +1,226 @@
+package authorizer
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func testHistory() []Transaction {
+	loc, _ := time.LoadLocation("Etc/GMT")
+	t1, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T11:00:00.000Z", loc)
+	t2, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T11:01:00.000Z", loc)
+	t3, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T11:00:30.000Z", loc)
+	t4, _ := time.ParseInLocation(time.RFC3339, "2019-02-13T11:05:00.000Z", loc)
+	return []Transaction{
+		{Merchant: "Burger King", Amount: 20, Time: t1},
+		{Merchant: "Habbib's", Amount: 10, Time: t2},
+		{Merchant: "McDonald's", Amount: 30, Time: t3},
+		{Merchant: "Subway", Amount: 5, Time: t4},
+	}
+}
+
+func testStorageQueryHistory(t *testing.T, storage Storage) {
+	history := testHistory()
+	// the decision is kept with the transaction
+	history[1].declined = true
+	for _, transaction := range history {
+		_ = storage.AppendHistory(defaultAccountID, transaction)
+	}
+	_ = storage.AppendHistory("other", history[0])
+
+	expected := []Transaction{history[2], history[1]}
+	result, err := storage.QueryHistory(defaultAccountID, history[0].Time.(time.Time), history[1].Time.(time.Time))
+
+	if err == nil && len(result) == len(expected) &&
+		result[0].Merchant == expected[0].Merchant && result[0].Time.(time.Time).Equal(expected[0].Time.(time.Time)) &&
+		result[1].Merchant == expected[1].Merchant && result[1].Time.(time.Time).Equal(expected[1].Time.(time.Time)) &&
+		!result[0].declined && result[1].declined {
+		t.Logf("QueryHistory(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("QueryHistory(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestMemoryStorageQueryHistory(t *testing.T) {
+	testStorageQueryHistory(t, NewMemoryStorage())
+}
+
+func TestFileStorageQueryHistory(t *testing.T) {
+	storage, err := OpenFileStorage(filepath.Join(t.TempDir(), "state.kv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	testStorageQueryHistory(t, storage)
+}
+
+func TestFileStorageReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.kv")
+
+	storage, err := OpenFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := AccountStatus{
+		account:    Account{ActiveCard: true, AvailableLimit: 80},
+		hasAccount: true,
+		initial:    Account{ActiveCard: true, AvailableLimit: 100},
+	}
+	_ = storage.PutAccount(defaultAccountID, AccountStatus{account: Account{ActiveCard: true, AvailableLimit: 100}, hasAccount: true})
+	_ = storage.PutAccount(defaultAccountID, expected)
+	_ = storage.AppendHistory(defaultAccountID, testHistory()[0])
+	_ = storage.Close()
+
+	// simulate a torn write at the end of the file
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	_, _ = file.Write([]byte{0, 1, 2})
+	_ = file.Close()
+
+	storage, err = OpenFileStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer storage.Close()
+
+	result, err := storage.GetAccount(defaultAccountID)
+	history, _ := storage.QueryHistory(defaultAccountID, testHistory()[0].Time.(time.Time).Add(-time.Minute), time.Time{})
+
+	if err == nil && reflect.DeepEqual(expected, result) && len(history) == 1 && storage.sequence == 1 {
+		t.Logf("OpenFileStorage(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("OpenFileStorage(...) FAILED \nexpected: %v \nresult: %v %v %v", expected, result, history, err)
+	}
+}
+
+func TestKVDelete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.kv")
+
+	kv, err := OpenKV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = kv.Put("a", []byte("1"))
+	_ = kv.Put("b", []byte("2"))
+	_ = kv.Put("c", []byte("3"))
+	_ = kv.Delete("b")
+	_ = kv.Close()
+
+	kv, err = OpenKV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer kv.Close()
+
+	expected := []string{"a=1", "c=3"}
+	var result []string
+	_ = kv.Scan("", "\xff", func(key string, value []byte) error {
+		result = append(result, key+"="+string(value))
+		return nil
+	})
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("KV.Scan(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("KV.Scan(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+// testKVFile writes 10 keys to a kv file and returns its content
+func testKVFile(t *testing.T, path string) []byte {
+	kv, err := OpenKV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		_ = kv.Put("key-"+strconv.Itoa(i), []byte("value"))
+	}
+	_ = kv.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestKVTornTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.kv")
+	data := testKVFile(t, path)
+
+	// the last record is cut short, as by a crash while appending it
+	_ = os.WriteFile(path, data[:len(data)-3], 0600)
+	kv, err := OpenKV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer kv.Close()
+
+	expected := []interface{}{9, int64(len(data) / 10 * 9)}
+	info, _ := os.Stat(path)
+	result := []interface{}{len(kv.Keys("", "\xff")), info.Size()}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("OpenKV(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("OpenKV(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestKVCorrupted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.kv")
+	data := testKVFile(t, path)
+
+	// a byte flipped in a record followed by others is not cut, the file is left as is
+	data[len(data)/2] ^= 0xff
+	_ = os.WriteFile(path, data, 0600)
+	_, err := OpenKV(path)
+	info, _ := os.Stat(path)
+
+	if err != nil && info.Size() == int64(len(data)) {
+		t.Logf("OpenKV(...) PASSED \nexpected: error \nresult: %v", err)
+	} else {
+		t.Errorf("OpenKV(...) FAILED \nexpected: error \nresult: %v %v", err, info.Size())
+	}
+}
+
+func TestKVCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.kv")
+
+	kv, err := OpenKV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv.compactSize = 0
+	for i := 0; i < 1000; i++ {
+		_ = kv.Put("account", []byte(strconv.Itoa(i)))
+		_ = kv.Put("history", []byte(strconv.Itoa(i)))
+		_ = kv.Delete("history")
+	}
+	size, live := kv.Size()
+	_ = kv.Close()
+
+	kv, err = OpenKV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer kv.Close()
+
+	// the file holds little more than the live value, which is still there once reopened
+	expected := []string{"account=999"}
+	var result []string
+	_ = kv.Scan("", "\xff", func(key string, value []byte) error {
+		result = append(result, key+"="+string(value))
+		return nil
+	})
+
+	if size <= 2*live && reflect.DeepEqual(expected, result) {
+		t.Logf("KV.Compact() PASSED \nexpected: %v \nresult: %v size=%d live=%d", expected, result, size, live)
+	} else {
+		t.Errorf("KV.Compact() FAILED \nexpected: %v \nresult: %v size=%d live=%d", expected, result, size, live)
+	}
+}
@@ -0,0 +1,134 @@
+package authorizer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MaxAmount bounds amounts and limits, it is the largest value of an ISO 8583 amount field.
+// It is an int64 so it also compiles where int is 32 bits, ints being compared to it as int64.
+const MaxAmount int64 = 999999999999
+
+var mccPattern = regexp.MustCompile(`^[0-9]{4}$`)
+
+// requiredFields of each operation type, as in the published schemas (schema/*.schema.json)
+var requiredFields = map[string][]string{
+	"account":     {"active-card", "available-limit"},
+	"transaction": {"merchant", "amount", "time"},
+}
+
+// ValidationError reports a field of an operation that is not valid
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (err *ValidationError) Error() string {
+	return err.Field + ": " + err.Message
+}
+
+// validateOperation checks the ranges of an AccountOperation or a TransactionOperation
+func validateOperation(operation interface{}) error {
+	switch operation := operation.(type) {
+	case AccountOperation:
+		limit := operation.Account.AvailableLimit
+		if limit < 0 || int64(limit) > MaxAmount {
+			return &ValidationError{"account.available-limit", fmt.Sprintf("must be between 0 and %d", MaxAmount)}
+		}
+	case TransactionOperation:
+		amount := operation.Transaction.Amount
+		if amount <= 0 || int64(amount) > MaxAmount {
+			return &ValidationError{"transaction.amount", fmt.Sprintf("must be between 1 and %d", MaxAmount)}
+		}
+		if _, ok := operation.Transaction.Time.(time.Time); !ok {
+			return &ValidationError{"transaction.time", "must be a RFC 3339 timestamp"}
+		}
+		if mcc := operation.Transaction.MCC; mcc != "" && !mccPattern.MatchString(mcc) {
+			return &ValidationError{"transaction.mcc", "must be 4 digits"}
+		}
+	default:
+		return errors.New("operation not valid")
+	}
+	return nil
+}
+
+// decodeStrict decodes a json line into an Operation, rejecting fields that are not in the schema,
+// missing required fields and anything after the operation
+func decodeStrict(line string) (Operation, error) {
+	var operation Operation
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&operation); err != nil {
+		return operation, err
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return operation, errors.New("unexpected data after the operation")
+	}
+
+	var objects map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(line), &objects); err != nil {
+		return operation, err
+	}
+	for name, required := range requiredFields {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(objects[name], &object); err != nil || object == nil {
+			continue
+		}
+		for _, field := range required {
+			if value, ok := object[field]; !ok || string(value) == "null" {
+				return operation, &ValidationError{name + "." + field, "is required"}
+			}
+		}
+	}
+
+	return operation, nil
+}
+
+type ValidationResult struct {
+	Valid   int
+	Invalid int
+}
+
+// Validate decodes every record of an input without processing it, reporting the records that are not valid to errs
+func Validate(reader io.Reader, format string, errs io.Writer) (ValidationResult, error) {
+	var result ValidationResult
+	decoder, err := NewDecoder(format, reader)
+	if err != nil {
+		return result, err
+	}
+
+	for ordinal := 1; ; ordinal++ {
+		value, err := decoder.Read()
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+		if err == nil {
+			_, err = decoder.Decode(value)
+		}
+
+		if err != nil {
+			result.Invalid++
+			fmt.Fprintf(errs, "line %d: %v\n", recordLine(decoder, ordinal), err)
+		} else {
+			result.Valid++
+		}
+	}
+}
+
+// liner is implemented by decoders knowing the input line of the last record read
+type liner interface {
+	Line() int
+}
+
+// recordLine returns the line of the last record read, its ordinal when the decoder does not know it
+func recordLine(decoder Decoder, ordinal int) int {
+	if liner, ok := decoder.(liner); ok {
+		return liner.Line()
+	}
+	return ordinal
+}
@@ -0,0 +1,197 @@
+package authorizer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestDecodeNotValid(t *testing.T) {
+	lines := map[string]string{
+		"unknown field":         `{"account": {"active-card": true, "available-limit": 100, "limit": 5}}`,
+		"unknown operation":     `{"refund": {"amount": 10}}`,
+		"both operations":       `{"account": {"active-card": true, "available-limit": 100}, "transaction": {"merchant": "a", "amount": 1, "time": "2019-02-13T10:00:00.000Z"}}`,
+		"missing time":          `{"transaction": {"merchant": "Burger King", "amount": 20}}`,
+		"null time":             `{"transaction": {"merchant": "Burger King", "amount": 20, "time": null}}`,
+		"numeric time":          `{"transaction": {"merchant": "Burger King", "amount": 20, "time": 1550052000}}`,
+		"time not valid":        `{"transaction": {"merchant": "Burger King", "amount": 20, "time": "yesterday"}}`,
+		"negative amount":       `{"transaction": {"merchant": "Burger King", "amount": -20, "time": "2019-02-13T10:00:00.000Z"}}`,
+		"zero amount":           `{"transaction": {"merchant": "Burger King", "amount": 0, "time": "2019-02-13T10:00:00.000Z"}}`,
+		"fractional amount":     `{"transaction": {"merchant": "Burger King", "amount": 20.5, "time": "2019-02-13T10:00:00.000Z"}}`,
+		"amount too large":      `{"transaction": {"merchant": "Burger King", "amount": 1000000000000, "time": "2019-02-13T10:00:00.000Z"}}`,
+		"mcc not valid":         `{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z", "mcc": "58"}}`,
+		"missing merchant":      `{"transaction": {"amount": 20, "time": "2019-02-13T10:00:00.000Z"}}`,
+		"negative limit":        `{"account": {"active-card": true, "available-limit": -100}}`,
+		"missing limit":         `{"account": {"active-card": true}}`,
+		"string active-card":    `{"account": {"active-card": "true", "available-limit": 100}}`,
+		"data after operation":  `{"account": {"active-card": true, "available-limit": 100}} {}`,
+		"not an object":         `[1, 2]`,
+		"empty operation":       `{}`,
+		"null account":          `{"account": null}`,
+		"account not an object": `{"account": 5}`,
+	}
+
+	for name, line := range lines {
+		operation, err := decode(line, nil)
+
+		if err != nil {
+			t.Logf("decode(%s) PASSED \nexpected: error \nresult: %v", name, err)
+		} else {
+			t.Errorf("decode(%s) FAILED \nexpected: error \nresult: %v", name, operation)
+		}
+	}
+}
+
+func TestDecodeValidationError(t *testing.T) {
+	expected := &ValidationError{Field: "transaction.amount", Message: "must be between 1 and 999999999999"}
+
+	_, err := decode(`{"transaction": {"merchant": "Burger King", "amount": -20, "time": "2019-02-13T10:00:00.000Z"}}`, nil)
+	var result *ValidationError
+
+	if errors.As(err, &result) && reflect.DeepEqual(expected, result) {
+		t.Logf("decode(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("decode(...) FAILED \nexpected: %v \nresult: %v", expected, err)
+	}
+}
+
+func TestAuthorizerApplyNegativeAmount(t *testing.T) {
+	authorize := New(Options{})
+	_, _ = authorize.Apply(Operation{Account: &Account{ActiveCard: true, AvailableLimit: 100}})
+
+	_, err := authorize.Apply(Operation{Transaction: &Transaction{
+		Merchant: "Burger King", Amount: -20, Time: "2019-02-13T10:00:00.000Z"}})
+	status, _ := authorize.State("")
+	expected := Account{ActiveCard: true, AvailableLimit: 100}
+
+	if errors.Is(err, ErrInvalidOperation) && reflect.DeepEqual(expected, status.Account()) {
+		t.Logf("Authorizer.Apply(...) PASSED \nexpected: %v \nresult: %v", expected, status.Account())
+	} else {
+		t.Errorf("Authorizer.Apply(...) FAILED \nexpected: %v \nresult: %v %v", expected, status.Account(), err)
+	}
+}
+
+func TestAuthorizerRunErrorLines(t *testing.T) {
+	input := `{"account": {"active-card": true, "available-limit": 100}}
+{"transaction": {"merchant": "Burger King", "amount": 20}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z", "tip": 2}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}`
+
+	expected := `line 2: transaction.time: is required
+line 3: json: unknown field "tip"
+`
+
+	for _, workers := range []int{1, 4} {
+		var writer, errs bytes.Buffer
+		err := New(Options{Workers: workers, Errors: &errs}).Run(context.Background(), strings.NewReader(input), &writer)
+		result := errs.String()
+
+		if err == nil && expected == result && strings.Count(writer.String(), "\n") == 2 {
+			t.Logf("Authorizer.Run(workers=%d) PASSED \nexpected: %v \nresult: %v", workers, expected, result)
+		} else {
+			t.Errorf("Authorizer.Run(workers=%d) FAILED \nexpected: %v \nresult: %v %v", workers, expected, result, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	input := `{"account": {"active-card": true, "available-limit": 100}}
+{"transaction": {"merchant": "Burger King", "amount": -20, "time": "2019-02-13T10:00:00.000Z"}}
+{"transaction": {"merchant": "Burger King", "amount": 20, "time": "2019-02-13T10:00:00.000Z"}}`
+
+	expected := ValidationResult{Valid: 2, Invalid: 1}
+	expectedErrors := "line 2: transaction.amount: must be between 1 and 999999999999\n"
+
+	var errs bytes.Buffer
+	result, err := Validate(strings.NewReader(input), FormatNDJSON, &errs)
+
+	if err == nil && expected == result && expectedErrors == errs.String() {
+		t.Logf("Validate(...) PASSED \nexpected: %v %v \nresult: %v %v", expected, expectedErrors, result, errs.String())
+	} else {
+		t.Errorf("Validate(...) FAILED \nexpected: %v %v \nresult: %v %v %v", expected, expectedErrors, result, errs.String(), err)
+	}
+}
+
+func TestValidateCSV(t *testing.T) {
+	input := `type,account-id,active-card,available-limit,merchant,amount,time
+account,1,true,-100,,,
+transaction,1,,,Burger King,20,2019-02-13T10:00:00.000Z`
+
+	expected := ValidationResult{Valid: 1, Invalid: 1}
+	expectedErrors := "line 2: account.available-limit: must be between 0 and 999999999999\n"
+
+	var errs bytes.Buffer
+	result, err := Validate(strings.NewReader(input), FormatCSV, &errs)
+
+	if err == nil && expected == result && expectedErrors == errs.String() {
+		t.Logf("Validate(...) PASSED \nexpected: %v %v \nresult: %v %v", expected, expectedErrors, result, errs.String())
+	} else {
+		t.Errorf("Validate(...) FAILED \nexpected: %v %v \nresult: %v %v %v", expected, expectedErrors, result, errs.String(), err)
+	}
+}
+
+type testSchema struct {
+	Properties map[string]struct {
+		Properties map[string]struct {
+			Minimum *int   `json:"minimum"`
+			Maximum *int64 `json:"maximum"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	} `json:"properties"`
+}
+
+func jsonFields(value interface{}) []string {
+	var fields []string
+	kind := reflect.TypeOf(value)
+	for i := 0; i < kind.NumField(); i++ {
+		// unexported fields are not decoded
+		if !kind.Field(i).IsExported() {
+			continue
+		}
+		fields = append(fields, strings.Split(kind.Field(i).Tag.Get("json"), ",")[0])
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// TestSchemas keeps the published schemas in line with the decoding
+func TestSchemas(t *testing.T) {
+	types := map[string]interface{}{"account": Account{}, "transaction": Transaction{}}
+
+	for name, value := range types {
+		data, err := os.ReadFile(filepath.Join("..", "schema", name+".schema.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var schema testSchema
+		if err := json.Unmarshal(data, &schema); err != nil {
+			t.Fatal(err)
+		}
+
+		object := schema.Properties[name]
+		var fields []string
+		for field, property := range object.Properties {
+			fields = append(fields, field)
+			if property.Maximum != nil && *property.Maximum != MaxAmount {
+				t.Errorf("schema %s FAILED \nexpected: %s maximum %d \nresult: %d", name, field, MaxAmount, *property.Maximum)
+			}
+		}
+		sort.Strings(fields)
+
+		expected := []interface{}{jsonFields(value), requiredFields[name]}
+		result := []interface{}{fields, object.Required}
+
+		if reflect.DeepEqual(expected, result) {
+			t.Logf("schema %s PASSED \nexpected: %v \nresult: %v", name, expected, result)
+		} else {
+			t.Errorf("schema %s FAILED \nexpected: %v \nresult: %v", name, expected, result)
+		}
+	}
+}
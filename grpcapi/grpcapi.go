@@ -0,0 +1,278 @@
+// Package grpcapi serves the authorizer over gRPC, as described by proto/authorizer.proto.
+// Messages are encoded with the authorizer protobuf codec, so no generated code is needed.
+package grpcapi
+
+import (
+	"Authorizer/authorizer"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const serviceName = "authorizer.Authorize"
+
+// ListReviewsRequest is the empty request of ListReviews
+type ListReviewsRequest struct{}
+
+// ReviewList holds the transactions waiting for a manual decision, in the order they were queued
+type ReviewList struct {
+	Reviews []authorizer.Review
+}
+
+// ReviewRequest names the review to approve or reject
+type ReviewRequest struct {
+	ID string
+}
+
+// Codec encodes the messages of the Authorize service, it is named "proto"
+// so that any gRPC client generated from proto/authorizer.proto can talk to the server
+type Codec struct{}
+
+func (Codec) Name() string {
+	return "proto"
+}
+
+func (Codec) Marshal(value interface{}) ([]byte, error) {
+	switch message := value.(type) {
+	case *authorizer.Operation:
+		return authorizer.MarshalOperation(*message)
+	case *authorizer.AccountOperationOutput:
+		return authorizer.MarshalOutput(*message), nil
+	case *ListReviewsRequest:
+		return nil, nil
+	case *ReviewList:
+		return authorizer.MarshalReviews(message.Reviews)
+	case *ReviewRequest:
+		return authorizer.MarshalReviewRequest(message.ID), nil
+	default:
+		return nil, fmt.Errorf("grpcapi: cannot marshal %T", value)
+	}
+}
+
+func (Codec) Unmarshal(data []byte, value interface{}) error {
+	var err error
+	switch message := value.(type) {
+	case *authorizer.Operation:
+		*message, err = authorizer.UnmarshalOperation(data)
+	case *authorizer.AccountOperationOutput:
+		*message, err = authorizer.UnmarshalOutput(data)
+	case *ListReviewsRequest:
+	case *ReviewList:
+		message.Reviews, err = authorizer.UnmarshalReviews(data)
+	case *ReviewRequest:
+		message.ID, err = authorizer.UnmarshalReviewRequest(data)
+	default:
+		err = fmt.Errorf("grpcapi: cannot unmarshal %T", value)
+	}
+	return err
+}
+
+type service struct {
+	authorizer *authorizer.Authorizer
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Authorize", Handler: authorizeHandler},
+		{MethodName: "ListReviews", Handler: listReviewsHandler},
+		{MethodName: "ApproveReview", Handler: reviewHandler("ApproveReview", authorizer.DecisionApproved)},
+		{MethodName: "RejectReview", Handler: reviewHandler("RejectReview", authorizer.DecisionDeclined)},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "AuthorizeStream", Handler: authorizeStreamHandler, ServerStreams: true, ClientStreams: true},
+	},
+	Metadata: "proto/authorizer.proto",
+}
+
+// NewServer returns a gRPC server using the authorizer codec with the Authorize service registered
+func NewServer(authorize *authorizer.Authorizer, options ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(append([]grpc.ServerOption{grpc.ForceServerCodec(Codec{})}, options...)...)
+	Register(server, authorize)
+	return server
+}
+
+// Register adds the Authorize service to a gRPC server, which must use the authorizer Codec
+func Register(server *grpc.Server, authorize *authorizer.Authorizer) {
+	server.RegisterService(&serviceDesc, &service{authorizer: authorize})
+}
+
+func (service *service) apply(operation authorizer.Operation) (*authorizer.AccountOperationOutput, error) {
+	output, err := service.authorizer.Apply(operation)
+	if errors.Is(err, authorizer.ErrInvalidOperation) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &output, nil
+}
+
+func authorizeHandler(server interface{}, ctx context.Context, decode func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	operation := new(authorizer.Operation)
+	if err := decode(operation); err != nil {
+		return nil, err
+	}
+
+	handler := func(ctx context.Context, request interface{}) (interface{}, error) {
+		return server.(*service).apply(*request.(*authorizer.Operation))
+	}
+	if interceptor == nil {
+		return handler(ctx, operation)
+	}
+	info := &grpc.UnaryServerInfo{Server: server, FullMethod: "/" + serviceName + "/Authorize"}
+	return interceptor(ctx, operation, info, handler)
+}
+
+// resolve approves or rejects a queued review, answering with the account once its hold is released
+func (service *service) resolve(id string, decision string) (*authorizer.AccountOperationOutput, error) {
+	resolve := service.authorizer.ApproveReview
+	if decision == authorizer.DecisionDeclined {
+		resolve = service.authorizer.RejectReview
+	}
+	account, err := resolve(id)
+	if errors.Is(err, authorizer.ErrReviewNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &authorizer.AccountOperationOutput{Account: account.Account(), Decision: decision, ID: id}, nil
+}
+
+func listReviewsHandler(server interface{}, ctx context.Context, decode func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	request := new(ListReviewsRequest)
+	if err := decode(request); err != nil {
+		return nil, err
+	}
+
+	handler := func(ctx context.Context, request interface{}) (interface{}, error) {
+		reviews, err := server.(*service).authorizer.Reviews()
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		return &ReviewList{Reviews: reviews}, nil
+	}
+	if interceptor == nil {
+		return handler(ctx, request)
+	}
+	info := &grpc.UnaryServerInfo{Server: server, FullMethod: "/" + serviceName + "/ListReviews"}
+	return interceptor(ctx, request, info, handler)
+}
+
+// reviewHandler returns the handler of the method resolving reviews with the given decision
+func reviewHandler(method string, decision string) func(interface{}, context.Context, func(interface{}) error,
+	grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(server interface{}, ctx context.Context, decode func(interface{}) error,
+		interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		request := new(ReviewRequest)
+		if err := decode(request); err != nil {
+			return nil, err
+		}
+
+		handler := func(ctx context.Context, request interface{}) (interface{}, error) {
+			return server.(*service).resolve(request.(*ReviewRequest).ID, decision)
+		}
+		if interceptor == nil {
+			return handler(ctx, request)
+		}
+		info := &grpc.UnaryServerInfo{Server: server, FullMethod: "/" + serviceName + "/" + method}
+		return interceptor(ctx, request, info, handler)
+	}
+}
+
+// authorizeStreamHandler answers every operation of the stream in order,
+// it ends the stream on the first operation that is not valid
+func authorizeStreamHandler(server interface{}, stream grpc.ServerStream) error {
+	for {
+		var operation authorizer.Operation
+		err := stream.RecvMsg(&operation)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		output, err := server.(*service).apply(operation)
+		if err != nil {
+			return err
+		}
+		if err := stream.SendMsg(output); err != nil {
+			return err
+		}
+	}
+}
+
+// Client calls the Authorize service
+type Client struct {
+	conn grpc.ClientConnInterface
+}
+
+func NewClient(conn grpc.ClientConnInterface) *Client {
+	return &Client{conn: conn}
+}
+
+func (client *Client) Authorize(ctx context.Context, operation authorizer.Operation) (authorizer.AccountOperationOutput, error) {
+	var output authorizer.AccountOperationOutput
+	err := client.conn.Invoke(ctx, "/"+serviceName+"/Authorize", &operation, &output, grpc.ForceCodec(Codec{}))
+	return output, err
+}
+
+// ListReviews returns the transactions waiting for a manual decision, in the order they were queued
+func (client *Client) ListReviews(ctx context.Context) ([]authorizer.Review, error) {
+	var list ReviewList
+	err := client.conn.Invoke(ctx, "/"+serviceName+"/ListReviews", &ListReviewsRequest{}, &list, grpc.ForceCodec(Codec{}))
+	return list.Reviews, err
+}
+
+// ApproveReview approves a queued transaction, a review that is not queued is answered with NotFound
+func (client *Client) ApproveReview(ctx context.Context, id string) (authorizer.AccountOperationOutput, error) {
+	var output authorizer.AccountOperationOutput
+	err := client.conn.Invoke(ctx, "/"+serviceName+"/ApproveReview", &ReviewRequest{ID: id}, &output, grpc.ForceCodec(Codec{}))
+	return output, err
+}
+
+// RejectReview declines a queued transaction, giving its amount back to the limit of the account
+func (client *Client) RejectReview(ctx context.Context, id string) (authorizer.AccountOperationOutput, error) {
+	var output authorizer.AccountOperationOutput
+	err := client.conn.Invoke(ctx, "/"+serviceName+"/RejectReview", &ReviewRequest{ID: id}, &output, grpc.ForceCodec(Codec{}))
+	return output, err
+}
+
+// AuthorizeStream opens a stream where each sent operation is answered in order
+func (client *Client) AuthorizeStream(ctx context.Context) (*Stream, error) {
+	stream, err := client.conn.NewStream(ctx, &serviceDesc.Streams[0], "/"+serviceName+"/AuthorizeStream", grpc.ForceCodec(Codec{}))
+	if err != nil {
+		return nil, err
+	}
+	return &Stream{stream: stream}, nil
+}
+
+type Stream struct {
+	stream grpc.ClientStream
+}
+
+func (stream *Stream) Send(operation authorizer.Operation) error {
+	return stream.stream.SendMsg(&operation)
+}
+
+// Recv returns the next output, io.EOF once the server ended the stream
+func (stream *Stream) Recv() (authorizer.AccountOperationOutput, error) {
+	var output authorizer.AccountOperationOutput
+	err := stream.stream.RecvMsg(&output)
+	return output, err
+}
+
+// CloseSend tells the server no more operations will be sent
+func (stream *Stream) CloseSend() error {
+	return stream.stream.CloseSend()
+}
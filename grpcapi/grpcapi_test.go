@@ -0,0 +1,174 @@
+package grpcapi
+
+import (
+	"Authorizer/authorizer"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestClient(t *testing.T) *Client {
+	return newAuthorizerClient(t, authorizer.New(authorizer.Options{}))
+}
+
+func newAuthorizerClient(t *testing.T, authorize *authorizer.Authorizer) *Client {
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(authorize)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return NewClient(conn)
+}
+
+func TestAuthorize(t *testing.T) {
+	client := newTestClient(t)
+	ctx := context.Background()
+
+	_, _ = client.Authorize(ctx, authorizer.Operation{Account: &authorizer.Account{ActiveCard: true, AvailableLimit: 100}})
+	result, err := client.Authorize(ctx, authorizer.Operation{ID: "t-1", Transaction: &authorizer.Transaction{
+		Merchant: "Burger King", Amount: 120, Time: "2019-02-13T10:00:00.000Z"}})
+
+	expected := authorizer.AccountOperationOutput{
+		Account:    authorizer.Account{ActiveCard: true, AvailableLimit: 100},
+		Violations: []string{"insufficient-limit"},
+		Decision:   authorizer.DecisionDeclined,
+		ID:         "t-1",
+	}
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("Client.Authorize(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Client.Authorize(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestAuthorizeNotValid(t *testing.T) {
+	client := newTestClient(t)
+
+	_, err := client.Authorize(context.Background(), authorizer.Operation{})
+
+	if status.Code(err) == codes.InvalidArgument {
+		t.Logf("Client.Authorize(...) PASSED \nexpected: %v \nresult: %v", codes.InvalidArgument, err)
+	} else {
+		t.Errorf("Client.Authorize(...) FAILED \nexpected: %v \nresult: %v", codes.InvalidArgument, err)
+	}
+}
+
+func TestAuthorizeStream(t *testing.T) {
+	client := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream, err := client.AuthorizeStream(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	operations := []authorizer.Operation{
+		{Account: &authorizer.Account{ActiveCard: true, AvailableLimit: 100}},
+		{Transaction: &authorizer.Transaction{Merchant: "Burger King", Amount: 20, Time: "2019-02-13T10:00:00.000Z"}},
+		{Transaction: &authorizer.Transaction{Merchant: "Burger King", Amount: 20, Time: "2019-02-13T10:00:01.000Z"}},
+		{Account: &authorizer.Account{ID: "b", ActiveCard: true, AvailableLimit: 50}},
+	}
+	for _, operation := range operations {
+		if err := stream.Send(operation); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.CloseSend(); err != nil {
+		t.Fatal(err)
+	}
+
+	var result []authorizer.AccountOperationOutput
+	for {
+		output, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, output)
+	}
+
+	expected := []authorizer.AccountOperationOutput{
+		{Account: authorizer.Account{ActiveCard: true, AvailableLimit: 100}, Decision: authorizer.DecisionApproved},
+		{Account: authorizer.Account{ActiveCard: true, AvailableLimit: 80}, Decision: authorizer.DecisionApproved},
+		{Account: authorizer.Account{ActiveCard: true, AvailableLimit: 80}, Violations: []string{"doubled-transaction"},
+			Decision: authorizer.DecisionDeclined},
+		{Account: authorizer.Account{ID: "b", ActiveCard: true, AvailableLimit: 50}, Decision: authorizer.DecisionApproved},
+	}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("Stream.Recv(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Stream.Recv(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestReviews(t *testing.T) {
+	// doubled transactions are sent to review
+	rules := authorizer.DefaultRules()
+	rules.Policy.Severities = map[string]string{authorizer.DoubledTransaction: authorizer.SeveritySoftDecline}
+	client := newAuthorizerClient(t, authorizer.New(authorizer.Options{Rules: &rules}))
+	ctx := context.Background()
+
+	_, _ = client.Authorize(ctx, authorizer.Operation{Account: &authorizer.Account{ActiveCard: true, AvailableLimit: 100}})
+	for _, id := range []string{"t-1", "t-2", "t-3"} {
+		_, err := client.Authorize(ctx, authorizer.Operation{ID: id, Transaction: &authorizer.Transaction{
+			Merchant: "Burger King", Amount: 20, Time: "2019-02-13T11:00:00.000Z"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reviews, err := client.ListReviews(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, review := range reviews {
+		ids = append(ids, review.ID)
+	}
+	approved, approveErr := client.ApproveReview(ctx, "t-2")
+	rejected, rejectErr := client.RejectReview(ctx, "t-3")
+	_, notFoundErr := client.RejectReview(ctx, "t-3")
+	remaining, _ := client.ListReviews(ctx)
+
+	expected := []interface{}{
+		[]string{"t-2", "t-3"},
+		authorizer.AccountOperationOutput{Account: authorizer.Account{ActiveCard: true, AvailableLimit: 40},
+			Decision: authorizer.DecisionApproved, ID: "t-2"},
+		authorizer.AccountOperationOutput{Account: authorizer.Account{ActiveCard: true, AvailableLimit: 60},
+			Decision: authorizer.DecisionDeclined, ID: "t-3"},
+		codes.NotFound,
+		0,
+	}
+	result := []interface{}{ids, approved, rejected, status.Code(notFoundErr), len(remaining)}
+
+	if approveErr == nil && rejectErr == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("Client.ListReviews(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Client.ListReviews(...) FAILED \nexpected: %v \nresult: %v %v %v", expected, result, approveErr, rejectErr)
+	}
+}
@@ -0,0 +1,198 @@
+package iso8583
+
+import (
+	"Authorizer/authorizer"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	MTIAuthorizationRequest  = "0100"
+	MTIAuthorizationResponse = "0110"
+)
+
+// response codes (field 39) of the 0110 messages
+const (
+	ResponseApproved          = "00"
+	ResponseReferToIssuer     = "01"
+	ResponseDoNotHonor        = "05"
+	ResponseInvalidCard       = "14"
+	ResponseFormatError       = "30"
+	ResponseInsufficientFunds = "51"
+	ResponseSuspectedFraud    = "59"
+	ResponseRestrictedCard    = "62"
+	ResponseFrequencyExceeded = "65"
+	ResponseDuplicate         = "94"
+	ResponseSystemError       = "96"
+)
+
+var responseCodes = map[string]string{
+	authorizer.AccountNotInitialized:      ResponseInvalidCard,
+	authorizer.CardNotActive:              ResponseRestrictedCard,
+	authorizer.InsufficientLimit:          ResponseInsufficientFunds,
+	authorizer.HighFrequencySmallInterval: ResponseFrequencyExceeded,
+	authorizer.DoubledTransaction:         ResponseDuplicate,
+	authorizer.CardTestingSuspected:       ResponseSuspectedFraud,
+	authorizer.StructuringSuspected:       ResponseSuspectedFraud,
+	authorizer.AnomalousTransaction:       ResponseSuspectedFraud,
+	authorizer.HighFraudScore:             ResponseSuspectedFraud,
+}
+
+// echoedFields are copied from the request to the response
+var echoedFields = []int{
+	FieldPAN, FieldProcessingCode, FieldAmount, FieldTransmissionTime, FieldSTAN, FieldLocalTime, FieldLocalDate,
+	FieldAcquirerID, FieldRetrievalReference, FieldTerminalID, FieldCardAcceptorID, FieldCurrencyCode,
+}
+
+// merchantNameLength is the length of the name at the start of field 43, followed by the city and the country
+const merchantNameLength = 25
+
+// ResponseCode maps the first violation to a response code, approved when there is none
+func ResponseCode(violations []string) string {
+	if len(violations) == 0 {
+		return ResponseApproved
+	}
+	if code, ok := responseCodes[violations[0]]; ok {
+		return code
+	}
+	return ResponseDoNotHonor
+}
+
+type Options struct {
+	// Now gives the year missing from the transmission time, time.Now when nil
+	Now func() time.Time
+	// MinorUnits in a unit of the account limit, the amount field being in minor units (100 when zero)
+	MinorUnits int
+}
+
+// Adapter answers 0100 authorization requests with 0110 responses, the PAN being the account id
+type Adapter struct {
+	authorizer *authorizer.Authorizer
+	now        func() time.Time
+	minorUnits int
+	approvals  uint64
+}
+
+func NewAdapter(authorize *authorizer.Authorizer, options Options) *Adapter {
+	now := options.Now
+	if now == nil {
+		now = time.Now
+	}
+	minorUnits := options.MinorUnits
+	if minorUnits <= 0 {
+		minorUnits = 100
+	}
+	return &Adapter{authorizer: authorize, now: now, minorUnits: minorUnits}
+}
+
+// Handle decides an authorization request and returns the packed response.
+// Requests whose MTI cannot be read as 0100 return an error; 0100 requests with a field that cannot be
+// parsed or missing a field are answered with a format error, echoing the fields read.
+// When the authorizer fails the response (a system error) is returned along with the error.
+func (adapter *Adapter) Handle(data []byte) ([]byte, error) {
+	request, parseErr := Parse(data)
+	if request.MTI != MTIAuthorizationRequest {
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		return nil, fmt.Errorf("iso8583: mti %s not supported", request.MTI)
+	}
+
+	code := ResponseFormatError
+	var applyErr error
+	transaction, err := adapter.Transaction(request)
+	if parseErr == nil && err == nil {
+		var output authorizer.AccountOperationOutput
+		// the retrieval reference number identifies the operation in the audit log
+		operation := authorizer.Operation{ID: request.Fields[FieldRetrievalReference], Transaction: &transaction}
+		output, applyErr = adapter.authorizer.Apply(operation)
+		switch {
+		case errors.Is(applyErr, authorizer.ErrInvalidOperation):
+			code, applyErr = ResponseFormatError, nil
+		case applyErr != nil:
+			code = ResponseSystemError
+		case output.Decision == authorizer.DecisionApproved:
+			// warn-only violations do not decline
+			code = ResponseApproved
+		case output.Decision == authorizer.DecisionReview:
+			code = ResponseReferToIssuer
+		default:
+			code = ResponseCode(output.Violations)
+		}
+	}
+
+	response := Message{MTI: MTIAuthorizationResponse, Fields: map[int]string{FieldResponseCode: code}}
+	for _, field := range echoedFields {
+		if value, ok := request.Fields[field]; ok {
+			response.Fields[field] = value
+		}
+	}
+	if code == ResponseApproved {
+		response.Fields[FieldAuthorizationID] = fmt.Sprintf("%06d", atomic.AddUint64(&adapter.approvals, 1)%1000000)
+	}
+
+	packed, err := response.Pack()
+	if err != nil {
+		return nil, err
+	}
+	return packed, applyErr
+}
+
+// Transaction maps the PAN, amount, MCC, merchant name and transmission time of a request to a Transaction
+func (adapter *Adapter) Transaction(request Message) (authorizer.Transaction, error) {
+	var transaction authorizer.Transaction
+	for _, field := range []int{FieldPAN, FieldAmount, FieldTransmissionTime} {
+		if _, ok := request.Fields[field]; !ok {
+			return transaction, fmt.Errorf("iso8583: field %d missing", field)
+		}
+	}
+
+	amount, err := strconv.ParseInt(request.Fields[FieldAmount], 10, 64)
+	if err != nil {
+		return transaction, fmt.Errorf("iso8583: amount not valid: %v", err)
+	}
+	moment, err := transmissionTime(request.Fields[FieldTransmissionTime], adapter.now())
+	if err != nil {
+		return transaction, err
+	}
+
+	merchant := request.Fields[FieldCardAcceptorLocation]
+	if len(merchant) > merchantNameLength {
+		merchant = merchant[:merchantNameLength]
+	}
+	merchant = strings.TrimSpace(merchant)
+	if merchant == "" {
+		merchant = strings.TrimSpace(request.Fields[FieldCardAcceptorID])
+	}
+
+	units := int64(adapter.minorUnits)
+	transaction = authorizer.Transaction{
+		AccountID: request.Fields[FieldPAN],
+		Merchant:  merchant,
+		// rounded up, a limit never covers less than the amount requested
+		Amount: int((amount + units - 1) / units),
+		Time:   moment,
+		MCC:    request.Fields[FieldMCC],
+	}
+	return transaction, nil
+}
+
+// transmissionTime parses field 7 (MMDDhhmmss, UTC), taking the year of now unless that puts it
+// more than a day in the future, which happens around new year
+func transmissionTime(value string, now time.Time) (time.Time, error) {
+	moment, err := time.Parse("0102150405", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("iso8583: transmission time not valid: %v", err)
+	}
+
+	now = now.UTC()
+	moment = moment.AddDate(now.Year()-moment.Year(), 0, 0)
+	if moment.After(now.Add(24 * time.Hour)) {
+		moment = moment.AddDate(-1, 0, 0)
+	}
+	return moment, nil
+}
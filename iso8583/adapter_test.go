@@ -0,0 +1,192 @@
+package iso8583
+
+import (
+	"Authorizer/authorizer"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestAdapter() *Adapter {
+	authorize := authorizer.New(authorizer.Options{})
+	_, _ = authorize.Apply(authorizer.Operation{Account: &authorizer.Account{
+		ID: "4111111111111111", ActiveCard: true, AvailableLimit: 100}})
+
+	return NewAdapter(authorize, Options{Now: func() time.Time {
+		return time.Date(2019, 2, 13, 12, 0, 0, 0, time.UTC)
+	}})
+}
+
+func readSample(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// TestAdapterHandle replays recorded 0100 requests, in order, against their recorded 0110 responses
+func TestAdapterHandle(t *testing.T) {
+	adapter := newTestAdapter()
+	samples := []struct {
+		request  string
+		response string
+	}{
+		{"approved.0100", "approved.0110"},
+		{"approved.0100", "doubled-transaction.0110"},
+		{"insufficient-limit.0100", "insufficient-limit.0110"},
+		{"unknown-card.0100", "unknown-card.0110"},
+		{"missing-time.0100", "missing-time.0110"},
+	}
+
+	for _, sample := range samples {
+		expected := string(readSample(t, sample.response))
+		response, err := adapter.Handle(readSample(t, sample.request))
+		result := string(response)
+
+		if err == nil && expected == result {
+			t.Logf("Adapter.Handle(%s) PASSED \nexpected: %v \nresult: %v", sample.request, expected, result)
+		} else {
+			t.Errorf("Adapter.Handle(%s) FAILED \nexpected: %v \nresult: %v %v", sample.request, expected, result, err)
+		}
+	}
+}
+
+// TestAdapterHandlePolicy checks that a doubled transaction is approved when warn-only, and referred to the issuer
+// when soft-decline
+func TestAdapterHandlePolicy(t *testing.T) {
+	expected := []string{ResponseApproved, ResponseReferToIssuer}
+
+	var result []string
+	for _, severity := range []string{authorizer.SeverityWarnOnly, authorizer.SeveritySoftDecline} {
+		rules := authorizer.DefaultRules()
+		rules.Policy.Severities = map[string]string{authorizer.DoubledTransaction: severity}
+		authorize := authorizer.New(authorizer.Options{Rules: &rules})
+		_, _ = authorize.Apply(authorizer.Operation{Account: &authorizer.Account{
+			ID: "4111111111111111", ActiveCard: true, AvailableLimit: 100}})
+		adapter := NewAdapter(authorize, Options{Now: func() time.Time {
+			return time.Date(2019, 2, 13, 12, 0, 0, 0, time.UTC)
+		}})
+
+		_, _ = adapter.Handle(readSample(t, "approved.0100"))
+		response, err := adapter.Handle(readSample(t, "approved.0100"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		message, err := Parse(response)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, message.Fields[FieldResponseCode])
+	}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("Adapter.Handle(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Adapter.Handle(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+// TestAdapterHandleFormatError checks that a 0100 request with a field that cannot be parsed is answered
+// with a format error, echoing the fields read before it
+func TestAdapterHandleFormatError(t *testing.T) {
+	sample := readSample(t, "approved.0100")
+	requests := map[string][]byte{
+		"trailing bytes":     append(append([]byte{}, sample...), '0'),
+		"currency truncated": sample[:len(sample)-1],
+	}
+	fields, err := Parse(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, request := range requests {
+		expected := Message{MTI: MTIAuthorizationResponse, Fields: map[int]string{FieldResponseCode: ResponseFormatError}}
+		for _, field := range echoedFields {
+			if field != FieldCurrencyCode || name != "currency truncated" {
+				expected.Fields[field] = fields.Fields[field]
+			}
+		}
+
+		response, err := newTestAdapter().Handle(request)
+		result, parseErr := Parse(response)
+
+		if err == nil && parseErr == nil && reflect.DeepEqual(expected, result) {
+			t.Logf("Adapter.Handle(%s) PASSED \nexpected: %v \nresult: %v", name, expected, result)
+		} else {
+			t.Errorf("Adapter.Handle(%s) FAILED \nexpected: %v \nresult: %v %v", name, expected, result, err)
+		}
+	}
+}
+
+func TestAdapterHandleNotSupported(t *testing.T) {
+	request := readSample(t, "approved.0100")
+	copy(request, "0200")
+
+	_, err := newTestAdapter().Handle(request)
+
+	if err != nil {
+		t.Logf("Adapter.Handle(...) PASSED \nexpected: error \nresult: %v", err)
+	} else {
+		t.Errorf("Adapter.Handle(...) FAILED \nexpected: error \nresult: %v", err)
+	}
+}
+
+func TestAdapterTransaction(t *testing.T) {
+	request, err := Parse(readSample(t, "insufficient-limit.0100"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := newTestAdapter().Transaction(request)
+	expected := authorizer.Transaction{
+		AccountID: "4111111111111111",
+		Merchant:  "BURGER KING",
+		Amount:    120,
+		Time:      time.Date(2019, 2, 13, 10, 5, 0, 0, time.UTC),
+		MCC:       "5814",
+	}
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("Adapter.Transaction(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Adapter.Transaction(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestResponseCode(t *testing.T) {
+	expected := []string{ResponseApproved, ResponseInsufficientFunds, ResponseRestrictedCard, ResponseDoNotHonor}
+
+	result := []string{
+		ResponseCode(nil),
+		ResponseCode([]string{authorizer.InsufficientLimit, authorizer.DoubledTransaction}),
+		ResponseCode([]string{authorizer.CardNotActive}),
+		ResponseCode([]string{"unknown-violation"}),
+	}
+
+	if reflect.DeepEqual(expected, result) {
+		t.Logf("ResponseCode(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("ResponseCode(...) FAILED \nexpected: %v \nresult: %v", expected, result)
+	}
+}
+
+func TestTransmissionTime(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 5, 0, 0, time.UTC)
+	expected := []time.Time{
+		time.Date(2019, 12, 31, 23, 59, 0, 0, time.UTC),
+		time.Date(2020, 1, 1, 0, 4, 0, 0, time.UTC),
+	}
+
+	first, err1 := transmissionTime("1231235900", now)
+	second, err2 := transmissionTime("0101000400", now)
+	result := []time.Time{first, second}
+
+	if err1 == nil && err2 == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("transmissionTime(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("transmissionTime(...) FAILED \nexpected: %v \nresult: %v %v %v", expected, result, err1, err2)
+	}
+}
@@ -0,0 +1,85 @@
+package iso8583
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// messages are framed with a 2 bytes big endian length, as on most card network links
+const maxFrame = 1<<16 - 1
+
+// ReadFrame reads the next framed message, io.EOF when the input ends between messages
+func ReadFrame(reader io.Reader) ([]byte, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(reader, length[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errors.New("iso8583: frame length truncated")
+		}
+		return nil, err
+	}
+
+	data := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, errors.New("iso8583: frame truncated")
+	}
+	return data, nil
+}
+
+func WriteFrame(writer io.Writer, data []byte) error {
+	if len(data) > maxFrame {
+		return fmt.Errorf("iso8583: message of %d bytes exceeds %d bytes", len(data), maxFrame)
+	}
+
+	var length [2]byte
+	binary.BigEndian.PutUint16(length[:], uint16(len(data)))
+	if _, err := writer.Write(length[:]); err != nil {
+		return err
+	}
+	_, err := writer.Write(data)
+	return err
+}
+
+// Run answers every framed request read from reader, writing the framed responses to writer.
+// Requests that cannot be answered are reported to errs and skipped; an authorizer failure stops the run.
+// When ctx is done it stops before the next request and returns the ctx error.
+func (adapter *Adapter) Run(ctx context.Context, reader io.Reader, writer io.Writer, errs io.Writer) error {
+	input := bufio.NewReader(reader)
+	output := bufio.NewWriter(writer)
+	defer output.Flush()
+
+	for sequence := 1; ; sequence++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		request, err := ReadFrame(input)
+		if errors.Is(err, io.EOF) {
+			return output.Flush()
+		}
+		if err != nil {
+			return err
+		}
+
+		response, err := adapter.Handle(request)
+		if response == nil {
+			fmt.Fprintf(errs, "message %d: %v\n", sequence, err)
+			continue
+		}
+		if writeErr := WriteFrame(output, response); writeErr != nil {
+			return writeErr
+		}
+		if err != nil {
+			return err
+		}
+		// answers are not held back while waiting for the next request
+		if input.Buffered() == 0 {
+			if err := output.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package iso8583
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestAdapterRun(t *testing.T) {
+	var input bytes.Buffer
+	_ = WriteFrame(&input, readSample(t, "approved.0100"))
+	_ = WriteFrame(&input, []byte("0800"))
+	_ = WriteFrame(&input, readSample(t, "insufficient-limit.0100"))
+
+	var output, errs bytes.Buffer
+	err := newTestAdapter().Run(context.Background(), &input, &output, &errs)
+
+	var result []string
+	for output.Len() > 0 {
+		frame, err := ReadFrame(&output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, string(frame))
+	}
+	expected := []string{string(readSample(t, "approved.0110")), string(readSample(t, "insufficient-limit.0110"))}
+
+	if err == nil && reflect.DeepEqual(expected, result) && errs.String() == "message 2: iso8583: message too short\n" {
+		t.Logf("Adapter.Run(...) PASSED \nexpected: %v \nresult: %v %v", expected, result, errs.String())
+	} else {
+		t.Errorf("Adapter.Run(...) FAILED \nexpected: %v \nresult: %v %v %v", expected, result, errs.String(), err)
+	}
+}
+
+func TestReadFrameTruncated(t *testing.T) {
+	_, err := ReadFrame(bytes.NewReader([]byte{0, 10, '0', '1'}))
+
+	if err != nil {
+		t.Logf("ReadFrame(...) PASSED \nexpected: error \nresult: %v", err)
+	} else {
+		t.Errorf("ReadFrame(...) FAILED \nexpected: error \nresult: %v", err)
+	}
+}
@@ -0,0 +1,308 @@
+// Package iso8583 adapts ISO 8583 (1987, ASCII) authorization requests to the authorizer.
+package iso8583
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// fields of the messages used by the adapter
+const (
+	FieldPAN                  = 2
+	FieldProcessingCode       = 3
+	FieldAmount               = 4
+	FieldTransmissionTime     = 7
+	FieldSTAN                 = 11
+	FieldLocalTime            = 12
+	FieldLocalDate            = 13
+	FieldMCC                  = 18
+	FieldPOSEntryMode         = 22
+	FieldAcquirerID           = 32
+	FieldRetrievalReference   = 37
+	FieldAuthorizationID      = 38
+	FieldResponseCode         = 39
+	FieldTerminalID           = 41
+	FieldCardAcceptorID       = 42
+	FieldCardAcceptorLocation = 43
+	FieldCurrencyCode         = 49
+)
+
+// fieldSpec describes how a field is encoded: fixed length when prefix is zero,
+// else a variable length with a prefix of 2 (LLVAR) or 3 (LLLVAR) digits
+type fieldSpec struct {
+	length  int
+	prefix  int
+	numeric bool
+}
+
+// fieldSpecs is the field table of ISO 8583:1987, so that fields the adapter does not use are still skipped.
+// Binary fields (52, 64, 96 and 128) are carried as hexadecimal, two characters a byte, and the x+n amounts
+// (28 to 31, 97) as the C or D sign followed by the digits.
+var fieldSpecs = map[int]fieldSpec{
+	FieldPAN:                  {length: 19, prefix: 2, numeric: true},
+	FieldProcessingCode:       {length: 6, numeric: true},
+	FieldAmount:               {length: 12, numeric: true},
+	5:                         {length: 12, numeric: true}, // amount, settlement
+	6:                         {length: 12, numeric: true}, // amount, cardholder billing
+	FieldTransmissionTime:     {length: 10, numeric: true},
+	8:                         {length: 8, numeric: true}, // amount, cardholder billing fee
+	9:                         {length: 8, numeric: true}, // conversion rate, settlement
+	10:                        {length: 8, numeric: true}, // conversion rate, cardholder billing
+	FieldSTAN:                 {length: 6, numeric: true},
+	FieldLocalTime:            {length: 6, numeric: true},
+	FieldLocalDate:            {length: 4, numeric: true},
+	14:                        {length: 4, numeric: true}, // expiration date
+	15:                        {length: 4, numeric: true}, // settlement date
+	16:                        {length: 4, numeric: true}, // conversion date
+	17:                        {length: 4, numeric: true}, // capture date
+	FieldMCC:                  {length: 4, numeric: true},
+	19:                        {length: 3, numeric: true}, // acquiring institution country code
+	20:                        {length: 3, numeric: true}, // PAN extended country code
+	21:                        {length: 3, numeric: true}, // forwarding institution country code
+	FieldPOSEntryMode:         {length: 3, numeric: true},
+	23:                        {length: 3, numeric: true}, // card sequence number
+	24:                        {length: 3, numeric: true}, // network international identifier
+	25:                        {length: 2, numeric: true}, // point of service condition code
+	26:                        {length: 2, numeric: true}, // point of service PIN capture code
+	27:                        {length: 1, numeric: true}, // authorizing identification response length
+	28:                        {length: 9},                // amount, transaction fee
+	29:                        {length: 9},                // amount, settlement fee
+	30:                        {length: 9},                // amount, transaction processing fee
+	31:                        {length: 9},                // amount, settlement processing fee
+	FieldAcquirerID:           {length: 11, prefix: 2, numeric: true},
+	33:                        {length: 11, prefix: 2, numeric: true},  // forwarding institution id
+	34:                        {length: 28, prefix: 2},                 // PAN extended
+	35:                        {length: 37, prefix: 2},                 // track 2 data
+	36:                        {length: 104, prefix: 3, numeric: true}, // track 3 data
+	FieldRetrievalReference:   {length: 12},
+	FieldAuthorizationID:      {length: 6},
+	FieldResponseCode:         {length: 2},
+	40:                        {length: 3}, // service restriction code
+	FieldTerminalID:           {length: 8},
+	FieldCardAcceptorID:       {length: 15},
+	FieldCardAcceptorLocation: {length: 40},
+	44:                        {length: 25, prefix: 2},  // additional response data
+	45:                        {length: 76, prefix: 2},  // track 1 data
+	46:                        {length: 999, prefix: 3}, // additional data, ISO
+	47:                        {length: 999, prefix: 3}, // additional data, national
+	48:                        {length: 999, prefix: 3}, // additional data, private
+	FieldCurrencyCode:         {length: 3, numeric: true},
+	50:                        {length: 3},                            // currency code, settlement
+	51:                        {length: 3},                            // currency code, cardholder billing
+	52:                        {length: 16},                           // PIN data
+	53:                        {length: 16, numeric: true},            // security related control information
+	54:                        {length: 120, prefix: 3},               // additional amounts
+	55:                        {length: 999, prefix: 3},               // ICC data
+	56:                        {length: 999, prefix: 3},               // reserved, ISO
+	57:                        {length: 999, prefix: 3},               // reserved, national
+	58:                        {length: 999, prefix: 3},               // reserved, national
+	59:                        {length: 999, prefix: 3},               // reserved, national
+	60:                        {length: 999, prefix: 3},               // reserved, private
+	61:                        {length: 999, prefix: 3},               // reserved, private
+	62:                        {length: 999, prefix: 3},               // reserved, private
+	63:                        {length: 999, prefix: 3},               // reserved, private
+	64:                        {length: 16},                           // message authentication code
+	65:                        {length: 1},                            // bitmap, extended
+	66:                        {length: 1, numeric: true},             // settlement code
+	67:                        {length: 2, numeric: true},             // extended payment code
+	68:                        {length: 3, numeric: true},             // receiving institution country code
+	69:                        {length: 3, numeric: true},             // settlement institution country code
+	70:                        {length: 3, numeric: true},             // network management information code
+	71:                        {length: 4, numeric: true},             // message number
+	72:                        {length: 4, numeric: true},             // message number, last
+	73:                        {length: 6, numeric: true},             // date, action
+	74:                        {length: 10, numeric: true},            // credits, number
+	75:                        {length: 10, numeric: true},            // credits, reversal number
+	76:                        {length: 10, numeric: true},            // debits, number
+	77:                        {length: 10, numeric: true},            // debits, reversal number
+	78:                        {length: 10, numeric: true},            // transfer, number
+	79:                        {length: 10, numeric: true},            // transfer, reversal number
+	80:                        {length: 10, numeric: true},            // inquiries, number
+	81:                        {length: 10, numeric: true},            // authorizations, number
+	82:                        {length: 12, numeric: true},            // credits, processing fee amount
+	83:                        {length: 12, numeric: true},            // credits, transaction fee amount
+	84:                        {length: 12, numeric: true},            // debits, processing fee amount
+	85:                        {length: 12, numeric: true},            // debits, transaction fee amount
+	86:                        {length: 16, numeric: true},            // credits, amount
+	87:                        {length: 16, numeric: true},            // credits, reversal amount
+	88:                        {length: 16, numeric: true},            // debits, amount
+	89:                        {length: 16, numeric: true},            // debits, reversal amount
+	90:                        {length: 42, numeric: true},            // original data elements
+	91:                        {length: 1},                            // file update code
+	92:                        {length: 2},                            // file security code
+	93:                        {length: 5},                            // response indicator
+	94:                        {length: 7},                            // service indicator
+	95:                        {length: 42},                           // replacement amounts
+	96:                        {length: 16},                           // message security code
+	97:                        {length: 17},                           // amount, net settlement
+	98:                        {length: 25},                           // payee
+	99:                        {length: 11, prefix: 2, numeric: true}, // settlement institution id
+	100:                       {length: 11, prefix: 2, numeric: true}, // receiving institution id
+	101:                       {length: 17, prefix: 2},                // file name
+	102:                       {length: 28, prefix: 2},                // account id 1
+	103:                       {length: 28, prefix: 2},                // account id 2
+	104:                       {length: 100, prefix: 3},               // transaction description
+	105:                       {length: 999, prefix: 3},               // reserved
+	106:                       {length: 999, prefix: 3},               // reserved
+	107:                       {length: 999, prefix: 3},               // reserved
+	108:                       {length: 999, prefix: 3},               // reserved
+	109:                       {length: 999, prefix: 3},               // reserved
+	110:                       {length: 999, prefix: 3},               // reserved
+	111:                       {length: 999, prefix: 3},               // reserved
+	112:                       {length: 999, prefix: 3},               // reserved
+	113:                       {length: 999, prefix: 3},               // reserved
+	114:                       {length: 999, prefix: 3},               // reserved
+	115:                       {length: 999, prefix: 3},               // reserved
+	116:                       {length: 999, prefix: 3},               // reserved
+	117:                       {length: 999, prefix: 3},               // reserved
+	118:                       {length: 999, prefix: 3},               // reserved
+	119:                       {length: 999, prefix: 3},               // reserved
+	120:                       {length: 999, prefix: 3},               // reserved
+	121:                       {length: 999, prefix: 3},               // reserved
+	122:                       {length: 999, prefix: 3},               // reserved
+	123:                       {length: 999, prefix: 3},               // reserved
+	124:                       {length: 999, prefix: 3},               // reserved
+	125:                       {length: 999, prefix: 3},               // reserved
+	126:                       {length: 999, prefix: 3},               // reserved
+	127:                       {length: 999, prefix: 3},               // reserved
+	128:                       {length: 16},                           // message authentication code
+}
+
+// Message is an ISO 8583 message, fields are kept as their ASCII value
+type Message struct {
+	MTI    string
+	Fields map[int]string
+}
+
+// Parse reads a message made of the MTI, a hexadecimal bitmap (primary, and secondary when bit 1 is set)
+// and the fields in order. On an error, the message holds the MTI and the fields read before it.
+func Parse(data []byte) (Message, error) {
+	message := Message{Fields: map[int]string{}}
+	if len(data) < 4+16 {
+		return message, errors.New("iso8583: message too short")
+	}
+	message.MTI = string(data[:4])
+	if !isDigits(message.MTI) {
+		return message, fmt.Errorf("iso8583: mti %q not valid", message.MTI)
+	}
+	data = data[4:]
+
+	bitmap, err := strconv.ParseUint(string(data[:16]), 16, 64)
+	if err != nil {
+		return message, fmt.Errorf("iso8583: bitmap not valid: %v", err)
+	}
+	data = data[16:]
+	bitmaps := []uint64{bitmap}
+	if bitmap&(1<<63) != 0 {
+		if len(data) < 16 {
+			return message, errors.New("iso8583: secondary bitmap truncated")
+		}
+		secondary, err := strconv.ParseUint(string(data[:16]), 16, 64)
+		if err != nil {
+			return message, fmt.Errorf("iso8583: secondary bitmap not valid: %v", err)
+		}
+		data = data[16:]
+		bitmaps = append(bitmaps, secondary)
+	}
+
+	for i, bitmap := range bitmaps {
+		for bit := 0; bit < 64; bit++ {
+			field := i*64 + bit + 1
+			if bitmap&(1<<(63-bit)) == 0 || field == 1 {
+				continue
+			}
+
+			spec, ok := fieldSpecs[field]
+			if !ok {
+				return message, fmt.Errorf("iso8583: field %d not supported", field)
+			}
+
+			length := spec.length
+			if spec.prefix > 0 {
+				if len(data) < spec.prefix {
+					return message, fmt.Errorf("iso8583: field %d truncated", field)
+				}
+				length, err = strconv.Atoi(string(data[:spec.prefix]))
+				if err != nil || length > spec.length {
+					return message, fmt.Errorf("iso8583: field %d length not valid", field)
+				}
+				data = data[spec.prefix:]
+			}
+			if len(data) < length {
+				return message, fmt.Errorf("iso8583: field %d truncated", field)
+			}
+
+			value := string(data[:length])
+			if spec.numeric && !isDigits(value) {
+				return message, fmt.Errorf("iso8583: field %d is not numeric", field)
+			}
+			message.Fields[field] = value
+			data = data[length:]
+		}
+	}
+
+	if len(data) > 0 {
+		return message, fmt.Errorf("iso8583: %d bytes after the last field", len(data))
+	}
+	return message, nil
+}
+
+// Pack encodes a message, padding fixed length fields (zeros on the left for numeric ones, spaces on the right otherwise)
+func (message Message) Pack() ([]byte, error) {
+	if len(message.MTI) != 4 || !isDigits(message.MTI) {
+		return nil, fmt.Errorf("iso8583: mti %q not valid", message.MTI)
+	}
+
+	fields := make([]int, 0, len(message.Fields))
+	for field := range message.Fields {
+		fields = append(fields, field)
+	}
+	sort.Ints(fields)
+
+	var bitmaps [2]uint64
+	var body strings.Builder
+	for _, field := range fields {
+		spec, ok := fieldSpecs[field]
+		if !ok {
+			return nil, fmt.Errorf("iso8583: field %d not supported", field)
+		}
+
+		value := message.Fields[field]
+		if len(value) > spec.length {
+			return nil, fmt.Errorf("iso8583: field %d longer than %d", field, spec.length)
+		}
+		if spec.numeric && !isDigits(value) {
+			return nil, fmt.Errorf("iso8583: field %d is not numeric", field)
+		}
+
+		switch {
+		case spec.prefix > 0:
+			fmt.Fprintf(&body, "%0*d%s", spec.prefix, len(value), value)
+		case spec.numeric:
+			body.WriteString(strings.Repeat("0", spec.length-len(value)) + value)
+		default:
+			fmt.Fprintf(&body, "%-*s", spec.length, value)
+		}
+
+		bitmaps[(field-1)/64] |= 1 << (63 - (field-1)%64)
+	}
+
+	header := fmt.Sprintf("%s%016X", message.MTI, bitmaps[0])
+	if bitmaps[1] != 0 {
+		bitmaps[0] |= 1 << 63
+		header = fmt.Sprintf("%s%016X%016X", message.MTI, bitmaps[0], bitmaps[1])
+	}
+	return []byte(header + body.String()), nil
+}
+
+func isDigits(value string) bool {
+	for _, char := range value {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,120 @@
+package iso8583
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	expected := Message{MTI: "0100", Fields: map[int]string{
+		FieldPAN:                  "4111111111111111",
+		FieldProcessingCode:       "000000",
+		FieldAmount:               "000000002000",
+		FieldTransmissionTime:     "0213100000",
+		FieldSTAN:                 "000001",
+		FieldLocalTime:            "100000",
+		FieldLocalDate:            "0213",
+		FieldMCC:                  "5814",
+		FieldPOSEntryMode:         "051",
+		FieldAcquirerID:           "123456",
+		FieldRetrievalReference:   "904410000001",
+		FieldTerminalID:           "TERM0001",
+		FieldCardAcceptorID:       "MERCHANT0000001",
+		FieldCardAcceptorLocation: "BURGER KING              SAO PAULO    BR",
+		FieldCurrencyCode:         "986",
+	}}
+
+	result, err := Parse(readSample(t, "approved.0100"))
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("Parse(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Parse(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestParseStandardFields(t *testing.T) {
+	// fields the adapter does not use are read by the spec of ISO 8583:1987
+	expected := Message{MTI: "0100", Fields: map[int]string{
+		FieldPAN:              "4111111111111111",
+		FieldAmount:           "000000002000",
+		FieldTransmissionTime: "0213100000",
+		14:                    "2512",
+		35:                    "4111111111111111=25121010000000000",
+		48:                    "ADDITIONAL DATA",
+		52:                    "0123456789ABCDEF",
+		60:                    "RESERVED 60",
+		61:                    "RESERVED 61",
+		62:                    "RESERVED 62",
+		63:                    "RESERVED 63",
+		102:                   "ACCOUNT 1",
+	}}
+
+	packed, err := expected.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Parse(packed)
+
+	if err == nil && reflect.DeepEqual(expected, result) {
+		t.Logf("Parse(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Parse(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestPack(t *testing.T) {
+	for _, name := range []string{"approved.0100", "missing-time.0100", "approved.0110", "missing-time.0110"} {
+		expected := string(readSample(t, name))
+		message, err := Parse([]byte(expected))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		packed, err := message.Pack()
+		result := string(packed)
+
+		if err == nil && expected == result {
+			t.Logf("Pack(Parse(%s)) PASSED \nexpected: %v \nresult: %v", name, expected, result)
+		} else {
+			t.Errorf("Pack(Parse(%s)) FAILED \nexpected: %v \nresult: %v %v", name, expected, result, err)
+		}
+	}
+}
+
+func TestPackPadding(t *testing.T) {
+	expected := "0110" + "1000000002000000" + "000000000120" + "05"
+
+	packed, err := Message{MTI: "0110", Fields: map[int]string{FieldAmount: "120", FieldResponseCode: "05"}}.Pack()
+	result := string(packed)
+
+	if err == nil && expected == result {
+		t.Logf("Pack(...) PASSED \nexpected: %v \nresult: %v", expected, result)
+	} else {
+		t.Errorf("Pack(...) FAILED \nexpected: %v \nresult: %v %v", expected, result, err)
+	}
+}
+
+func TestParseNotValid(t *testing.T) {
+	sample := string(readSample(t, "approved.0100"))
+	messages := map[string]string{
+		"truncated":        sample[:len(sample)-1],
+		"trailing bytes":   sample + "0",
+		"mti":              "01A0" + sample[4:],
+		"bitmap":           sample[:4] + "Z" + sample[5:],
+		"not numeric":      sample[:22] + "41111111111111X1" + sample[38:],
+		"binary truncated": "0100" + "0000000000000001" + "01234567",
+		"llvar too long":   "0100" + "4000000000000000" + "20" + "41111111111111111111",
+		"secondary bitmap": "0100" + "8000000000000000" + "00",
+	}
+
+	for name, message := range messages {
+		_, err := Parse([]byte(message))
+
+		if err != nil {
+			t.Logf("Parse(%s) PASSED \nexpected: error \nresult: %v", name, err)
+		} else {
+			t.Errorf("Parse(%s) FAILED \nexpected: error \nresult: %v", name, err)
+		}
+	}
+}
@@ -1,248 +1,647 @@
 package main
 
 import (
-	"bufio"
+	"Authorizer/authorizer"
+	"Authorizer/grpcapi"
+	"Authorizer/iso8583"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net"
 	"os"
-	"reflect"
-	"strings"
+	"os/signal"
+	"runtime"
+	"syscall"
 	"time"
 )
 
-type Account struct {
-	ActiveCard     bool `json:"active-card"`
-	AvailableLimit int  `json:"available-limit"`
-}
+// exitSignal plus the signal number is the exit code when stopped by SIGINT (130) or SIGTERM (143),
+// once the decisions already made have been written and the state persisted
+const exitSignal = 128
 
-type Transaction struct {
-	Merchant string      `json:"merchant"`
-	Amount   int         `json:"amount"`
-	Time     interface{} `json:"time"`
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "audit":
+			os.Exit(auditCommand(os.Args[2:]))
+		case "state":
+			os.Exit(stateCommand(os.Args[2:]))
+		case "serve":
+			os.Exit(serveCommand(os.Args[2:]))
+		case "iso8583":
+			os.Exit(iso8583Command(os.Args[2:]))
+		case "validate":
+			os.Exit(validateCommand(os.Args[2:]))
+		case "repl":
+			os.Exit(replCommand(os.Args[2:]))
+		case "features":
+			os.Exit(featuresCommand(os.Args[2:]))
+		case "generate":
+			os.Exit(generateCommand(os.Args[2:]))
+		case "bench":
+			os.Exit(benchCommand(os.Args[2:]))
+		case "review":
+			os.Exit(reviewCommand(os.Args[2:]))
+		}
+	}
+	os.Exit(authorizeCommand(os.Args[1:]))
 }
 
-type AccountOperation struct {
-	Account Account
+func authorizeCommand(args []string) int {
+	flags := flag.NewFlagSet("authorize", flag.ExitOnError)
+	auditPath := flags.String("audit-log", "", "append every decision to a hash chained audit log file")
+	storagePath := flags.String("storage", "", "persist account state and history in an embedded key-value file")
+	stats := flags.Bool("stats", false, "print history retention statistics to stderr")
+	common := addOptionFlags(flags, true)
+	workers := flags.Int("workers", runtime.NumCPU(), "number of workers processing accounts in parallel")
+	inputFormat := flags.String("input-format", authorizer.FormatNDJSON, "format of the input: ndjson, csv or protobuf")
+	outputFormat := flags.String("output-format", authorizer.FormatNDJSON, "format of the output: ndjson, csv, table or protobuf")
+	_ = flags.Parse(args)
+
+	if _, err := authorizer.NewDecoder(*inputFormat, nil); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if _, err := authorizer.NewEncoder(*outputFormat, nil); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	options, err := common.options()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	options.Workers = *workers
+	// on stderr, so they never end up in a csv or protobuf output
+	options.Errors = os.Stderr
+	options.InputFormat = *inputFormat
+	options.OutputFormat = *outputFormat
+
+	closeAll, err := openPersistence(*auditPath, *storagePath, &options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer closeAll()
+
+	ctx, interrupted := notifySignals()
+
+	authorize := authorizer.New(options)
+	err = authorize.Run(ctx, os.Stdin, os.Stdout)
+
+	if *stats {
+		fmt.Fprintln(os.Stderr, authorize.Stats())
+	}
+
+	if errors.Is(err, context.Canceled) {
+		signal := <-interrupted
+		fmt.Fprintf(os.Stderr, "stopped by %v\n", signal)
+		return exitSignal + int(signal.(syscall.Signal))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
 }
 
-type TransactionOperation struct {
-	Transaction Transaction
+// loadRules sets the rules of the options from a rules file when its path is set
+func loadRules(path string, options *authorizer.Options) error {
+	if path == "" {
+		return nil
+	}
+	rules, err := authorizer.LoadRules(path)
+	if err != nil {
+		return err
+	}
+	options.Rules = &rules
+	return nil
 }
 
-type Operations struct {
-	input  []interface{}
-	output []AccountOperationOutput
+// optionFlags are the flags configuring the authorizer shared by the commands deciding operations
+type optionFlags struct {
+	retention  *time.Duration
+	maxHistory *int
+	rulesPath  *string
+	location   *string
+	// nil when the command has no clock
+	clockMode *string
 }
 
-type AccountOperationOutput struct {
-	Account    Account  `json:"account"`
-	Violations []string `json:"violations"`
+// addOptionFlags registers the retention, max-history, rules and location flags, and the clock flag when
+// the command keeps state over time
+func addOptionFlags(flags *flag.FlagSet, clock bool) *optionFlags {
+	common := &optionFlags{
+		retention:  flags.Duration("retention", 0, "how long transactions are kept in history (default: largest rule window)"),
+		maxHistory: flags.Int("max-history", 0, "maximum number of transactions kept in history per account (0 means no ceiling)"),
+		rulesPath:  flags.String("rules", "", "json file configuring the optional rules (default: built-in thresholds)"),
+		location:   flags.String("location", "", "time zone transaction times are parsed in (default: Etc/GMT)"),
+	}
+	if clock {
+		common.clockMode = flags.String("clock", authorizer.ClockEvent, "time the history retention follows: event (transaction times) or wall")
+	}
+	return common
 }
 
-type AccountStatus struct {
-	account    Account
-	hasAccount bool
+// options returns the options set by the flags
+func (common *optionFlags) options() (authorizer.Options, error) {
+	options := authorizer.Options{Retention: *common.retention, MaxHistory: *common.maxHistory}
+	if err := loadRules(*common.rulesPath, &options); err != nil {
+		return options, err
+	}
+	if common.clockMode != nil {
+		clock, err := authorizer.NewClock(*common.clockMode)
+		if err != nil {
+			return options, err
+		}
+		options.Clock = clock
+	}
+	if *common.location != "" {
+		location, err := time.LoadLocation(*common.location)
+		if err != nil {
+			return options, err
+		}
+		options.Location = location
+	}
+	return options, nil
 }
 
-const (
-	AccountNotInitialized      = "account-not-initialized"
-	AccountAlreadyInitialized  = "account-already-initialized"
-	CardNotActive              = "card-not-active"
-	DoubledTransaction         = "doubled-transaction"
-	InsufficientLimit          = "insufficient-limit"
-	HighFrequencySmallInterval = "high-frequency-small-interval"
-)
+// openPersistence opens the audit log and the storage when their paths are set,
+// the returned function closes them
+func openPersistence(auditPath string, storagePath string, options *authorizer.Options) (func(), error) {
+	var audit *authorizer.AuditLog
+	if auditPath != "" {
+		var err error
+		audit, err = authorizer.OpenAuditLog(auditPath)
+		if err != nil {
+			return nil, err
+		}
+		options.AuditLog = audit
+	}
 
-func main() {
-	scanner := bufio.NewScanner(os.Stdin)
+	var storage *authorizer.FileStorage
+	if storagePath != "" {
+		var err error
+		storage, err = authorizer.OpenFileStorage(storagePath)
+		if err != nil {
+			audit.Close()
+			return nil, err
+		}
+		options.Storage = storage
+	}
 
-	operations := process(scanner)
-	out := output(operations)
-	fmt.Println(out)
+	return func() {
+		if storage != nil {
+			storage.Close()
+		}
+		audit.Close()
+	}, nil
 }
 
-func output(slice []AccountOperationOutput) string {
-	var out []string
-	for i := range slice {
-		item := slice[i]
-		if item.Violations == nil {
-			item.Violations = make([]string, 0)
+func serveCommand(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := flags.String("listen", ":50051", "address the gRPC server listens on")
+	grace := flags.Duration("grace", 10*time.Second, "how long calls in progress may take to finish on shutdown")
+	auditPath := flags.String("audit-log", "", "append every decision to a hash chained audit log file")
+	storagePath := flags.String("storage", "", "persist account state and history in an embedded key-value file")
+	common := addOptionFlags(flags, true)
+	_ = flags.Parse(args)
+
+	if flags.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: authorize serve [--listen <address>] [--audit-log <file>] [--storage <file>]")
+		return 2
+	}
+
+	options, err := common.options()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	closeAll, err := openPersistence(*auditPath, *storagePath, &options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer closeAll()
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	server := grpcapi.NewServer(authorizer.New(options))
+	ctx, interrupted := notifySignals()
+	go func() {
+		<-ctx.Done()
+		// finishes the calls in progress, so their decisions are persisted, but streams that stay open
+		// are cut after a grace period
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(*grace):
+			server.Stop()
 		}
-		jsonData, _ := json.Marshal(item)
-		out = append(out, string(jsonData))
+	}()
+
+	fmt.Fprintf(os.Stderr, "serving on %v\n", listener.Addr())
+	if err := server.Serve(listener); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
-	return strings.Join(out[:], "\n")
+
+	signal := <-interrupted
+	fmt.Fprintf(os.Stderr, "stopped by %v\n", signal)
+	return exitSignal + int(signal.(syscall.Signal))
 }
 
-func processAccount(operation AccountOperation, accountStatus AccountStatus) AccountOperationOutput {
-	var violations []string
-	var activeCard bool
-	var availableLimit int
+func iso8583Command(args []string) int {
+	flags := flag.NewFlagSet("iso8583", flag.ExitOnError)
+	auditPath := flags.String("audit-log", "", "append every decision to a hash chained audit log file")
+	storagePath := flags.String("storage", "", "persist account state and history in an embedded key-value file")
+	minorUnits := flags.Int("minor-units", 100, "minor units of the amount field in a unit of the account limit")
+	common := addOptionFlags(flags, true)
+	_ = flags.Parse(args)
+
+	if flags.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: authorize iso8583 [--storage <file>] [--audit-log <file>] < requests")
+		return 2
+	}
 
-	if accountStatus.hasAccount && accountStatus.account.ActiveCard == operation.Account.ActiveCard {
-		violations = []string{AccountAlreadyInitialized}
-		activeCard = accountStatus.account.ActiveCard
-		availableLimit = accountStatus.account.AvailableLimit
-	} else {
-		activeCard = operation.Account.ActiveCard
-		availableLimit = operation.Account.AvailableLimit
+	options, err := common.options()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
 	}
 
-	return AccountOperationOutput{Account{
-		ActiveCard:     activeCard,
-		AvailableLimit: availableLimit,
-	}, violations}
+	closeAll, err := openPersistence(*auditPath, *storagePath, &options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer closeAll()
+
+	ctx, interrupted := notifySignals()
+
+	adapter := iso8583.NewAdapter(authorizer.New(options), iso8583.Options{MinorUnits: *minorUnits})
+	err = adapter.Run(ctx, os.Stdin, os.Stdout, os.Stderr)
+
+	if errors.Is(err, context.Canceled) {
+		signal := <-interrupted
+		fmt.Fprintf(os.Stderr, "stopped by %v\n", signal)
+		return exitSignal + int(signal.(syscall.Signal))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
 }
 
-func processTransaction(new TransactionOperation, status AccountStatus, operations []interface{}) AccountOperationOutput {
-	var violations []string
-	var account Account
+// notifySignals returns a context cancelled on the first SIGINT or SIGTERM, which is then sent on the channel.
+// Signals are reset afterwards, so a second one terminates the process right away.
+func notifySignals() (context.Context, <-chan os.Signal) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	interrupted := make(chan os.Signal, 1)
+
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		received := <-signals
+		signal.Reset(os.Interrupt, syscall.SIGTERM)
+		interrupted <- received
+		cancel()
+	}()
+
+	return ctx, interrupted
+}
 
-	if !status.hasAccount {
-		account.ActiveCard = false
-		account.AvailableLimit = 0
-		violations = []string{AccountNotInitialized}
-	} else {
-		account.ActiveCard = status.account.ActiveCard
-		account.AvailableLimit = status.account.AvailableLimit - new.Transaction.Amount
+func auditCommand(args []string) int {
+	if len(args) != 2 || args[0] != "verify" {
+		fmt.Fprintln(os.Stderr, "usage: authorize audit verify <file>")
+		return 2
+	}
 
-		if account.AvailableLimit < 0 {
-			violations = []string{InsufficientLimit}
-		}
+	file, err := os.Open(args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer file.Close()
 
-		if !status.account.ActiveCard {
-			violations = append(violations, CardNotActive)
-		}
+	count, err := authorizer.VerifyAuditLog(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "audit log is not valid: %v\n", err)
+		return 1
+	}
 
-		if hasDoubledTransaction(operations, new.Transaction) {
-			violations = append(violations, DoubledTransaction)
-		}
+	fmt.Printf("audit log is valid: %d records\n", count)
+	return 0
+}
 
-		if hasHighFrequencySmallInterval(operations, new.Transaction) {
-			violations = append(violations, HighFrequencySmallInterval)
-		}
+func validateCommand(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	inputFormat := flags.String("input-format", authorizer.FormatNDJSON, "format of the input: ndjson, csv or protobuf")
+	_ = flags.Parse(args)
 
-		if violations != nil {
-			account.AvailableLimit = status.account.AvailableLimit
-		}
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: authorize validate [--input-format <format>] <operations file>")
+		return 2
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
+	defer file.Close()
 
-	return AccountOperationOutput{account, violations}
+	result, err := authorizer.Validate(file, *inputFormat, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	fmt.Printf("%d operations valid, %d not valid\n", result.Valid, result.Invalid)
+	if result.Invalid > 0 {
+		return 1
+	}
+	return 0
 }
 
-func process(scanner *bufio.Scanner) []AccountOperationOutput {
-	var operations = Operations{}
-	var accountStatus = AccountStatus{
-		account:    Account{},
-		hasAccount: false,
+func replCommand(args []string) int {
+	flags := flag.NewFlagSet("repl", flag.ExitOnError)
+	common := addOptionFlags(flags, false)
+	_ = flags.Parse(args)
+
+	if flags.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: authorize repl [--retention <duration>] [--max-history <n>]")
+		return 2
 	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
+	options, err := common.options()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := authorizer.RunREPL(os.Stdin, os.Stdout, options); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
 
-		// set interface type for unstructured json
-		var result map[string]interface{}
-		err := json.Unmarshal([]byte(line), &result)
+func featuresCommand(args []string) int {
+	flags := flag.NewFlagSet("features", flag.ExitOnError)
+	inputFormat := flags.String("input-format", authorizer.FormatNDJSON, "format of the input: ndjson, csv or protobuf")
+	outputFormat := flags.String("output-format", authorizer.FormatCSV, "format of the features: csv, or json for one array per column")
+	rulesPath := flags.String("rules", "", "json file configuring the optional rules (default: built-in thresholds)")
+	_ = flags.Parse(args)
+
+	if flags.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "usage: authorize features [--input-format <format>] [--output-format csv|json] [--rules <file>] [operations file]")
+		return 2
+	}
+
+	options := authorizer.Options{}
+	if err := loadRules(*rulesPath, &options); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	input := os.Stdin
+	if flags.NArg() == 1 {
+		var err error
+		input, err = os.Open(flags.Arg(0))
 		if err != nil {
-			fmt.Println(err)
-			continue
+			fmt.Fprintln(os.Stderr, err)
+			return 1
 		}
+		defer input.Close()
+	}
+
+	_, err := authorizer.ExportFeatures(input, os.Stdout, authorizer.FeatureOptions{
+		InputFormat:  *inputFormat,
+		OutputFormat: *outputFormat,
+		Rules:        options.Rules,
+		Errors:       os.Stderr,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func generateCommand(args []string) int {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	seed := flags.Int64("seed", 1, "seed of the random stream, the same seed and options give the same stream")
+	accounts := flags.Int("accounts", 10, "number of accounts")
+	transactions := flags.Int("transactions", 1000, "number of transactions")
+	limit := flags.Int("limit", 10000, "available limit of every account")
+	merchants := flags.Int("merchants", 50, "number of merchants")
+	merchantSkew := flags.Float64("merchant-skew", 1.2, "exponent (> 1) of the Zipf distribution of merchants")
+	meanAmount := flags.Int("mean-amount", 50, "median of the log-normal distribution of amounts")
+	amountSpread := flags.Float64("amount-spread", 1, "sigma of the log-normal distribution of amounts")
+	meanInterval := flags.Duration("mean-interval", time.Minute, "mean time between transactions")
+	fraudRate := flags.Float64("fraud-rate", 0.02, "probability for a transaction to start a fraud pattern")
+	labelsPath := flags.String("labels", "", "write the label of every transaction to a CSV file")
+	_ = flags.Parse(args)
+
+	if flags.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: authorize generate [--seed <n>] [--accounts <n>] [--transactions <n>] [--labels <file>] ...")
+		return 2
+	}
 
-		switch true {
-		case result["account"] != nil: // check json structure match account structure
-			var accountOperation AccountOperation
-			err := json.Unmarshal([]byte(line), &accountOperation)
-			if err != nil {
-				fmt.Println(err)
-				continue
-			}
-
-			output := processAccount(accountOperation, accountStatus)
-
-			if output.Violations == nil {
-				accountStatus.hasAccount = true
-				accountStatus.account = output.Account
-			}
-
-			operations.input = append(operations.input, accountOperation)
-			operations.output = append(operations.output, output)
-		case result["transaction"] != nil: // check json structure match transaction structure
-			var transactionOperation TransactionOperation
-			err := json.Unmarshal([]byte(line), &transactionOperation)
-			if err != nil {
-				fmt.Println(err)
-				continue
-			}
-
-			// convert string to time
-			transactionOperation.Transaction.Time, _ = parseTime(transactionOperation.Transaction.Time.(string))
-
-			// get output
-			output := processTransaction(transactionOperation, accountStatus, operations.input)
-
-			if output.Violations == nil {
-				accountStatus.account.AvailableLimit = output.Account.AvailableLimit
-			}
-
-			operations.input = append(operations.input, transactionOperation)
-			operations.output = append(operations.output, output)
-		default:
-			fmt.Println("operation not valid")
+	var labels io.Writer
+	if *labelsPath != "" {
+		file, err := os.Create(*labelsPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
 		}
+		defer file.Close()
+		labels = file
 	}
 
-	return operations.output
+	err := authorizer.Generate(os.Stdout, labels, authorizer.GenerateOptions{
+		Seed:         *seed,
+		Accounts:     *accounts,
+		Transactions: *transactions,
+		Limit:        *limit,
+		Merchants:    *merchants,
+		MerchantSkew: *merchantSkew,
+		MeanAmount:   *meanAmount,
+		AmountSpread: *amountSpread,
+		MeanInterval: *meanInterval,
+		FraudRate:    fraudRate,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
 }
 
-func parseTime(data string) (time.Time, error) {
-	loc, _ := time.LoadLocation("Etc/GMT")
-	return time.ParseInLocation(time.RFC3339, data, loc)
+func benchCommand(args []string) int {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	seed := flags.Int64("seed", 1, "seed of the generated stream, keep it to compare runs")
+	accounts := flags.Int("accounts", 10, "number of accounts")
+	transactions := flags.Int("transactions", 100000, "number of transactions")
+	fraudRate := flags.Float64("fraud-rate", 0.02, "probability for a transaction to start a fraud pattern")
+	rulesPath := flags.String("rules", "", "json file configuring the optional rules (default: built-in thresholds)")
+	_ = flags.Parse(args)
+
+	if flags.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: authorize bench [--seed <n>] [--accounts <n>] [--transactions <n>] [--fraud-rate <p>] [--rules <file>]")
+		return 2
+	}
+
+	options := authorizer.Options{}
+	if err := loadRules(*rulesPath, &options); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	result, err := authorizer.Bench(authorizer.BenchOptions{
+		Generate: authorizer.GenerateOptions{Seed: *seed, Accounts: *accounts, Transactions: *transactions, FraudRate: fraudRate},
+		Rules:    options.Rules,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Printf("seed=%d accounts=%d transactions=%d fraud-rate=%v gomaxprocs=%d\n",
+		*seed, *accounts, *transactions, *fraudRate, runtime.GOMAXPROCS(0))
+	fmt.Println(result)
+	return 0
 }
 
-func hasDoubledTransaction(slice []interface{}, transaction Transaction) bool {
-	for i := len(slice) - 1; i >= 0; i-- {
-		if reflect.TypeOf(slice[i]).String() == "main.TransactionOperation" {
-			value := slice[i].(TransactionOperation).Transaction
-			if value.Merchant == transaction.Merchant && value.Amount == transaction.Amount {
-				if transaction.Time.(time.Time).Sub(value.Time.(time.Time)).Minutes() < 2 {
-					return true
-				}
-			}
+// reviewCommand lists the transactions waiting for review in a storage, or approves or rejects one of them
+func reviewCommand(args []string) int {
+	const usage = "usage: authorize review list|approve <id>|reject <id> --storage <file> [--audit-log <file>]"
+	var action, id string
+	switch {
+	case len(args) > 0 && args[0] == "list":
+		action, args = args[0], args[1:]
+	case len(args) > 1 && (args[0] == "approve" || args[0] == "reject"):
+		action, id, args = args[0], args[1], args[2:]
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	flags := flag.NewFlagSet("review", flag.ExitOnError)
+	storagePath := flags.String("storage", "", "embedded key-value file holding the review queue")
+	auditPath := flags.String("audit-log", "", "append every approval and rejection to a hash chained audit log file")
+	_ = flags.Parse(args)
+
+	if *storagePath == "" || flags.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+
+	var options authorizer.Options
+	closeAll, err := openPersistence(*auditPath, *storagePath, &options)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer closeAll()
+
+	authorize := authorizer.New(options)
+	if action == "list" {
+		reviews, err := authorize.Reviews()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
 		}
+		for _, review := range reviews {
+			jsonData, _ := json.Marshal(review)
+			fmt.Println(string(jsonData))
+		}
+		return 0
+	}
+
+	resolve := authorize.ApproveReview
+	if action == "reject" {
+		resolve = authorize.RejectReview
 	}
-	return false
+	status, err := resolve(id)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	jsonData, _ := json.Marshal(status)
+	fmt.Println(string(jsonData))
+	return 0
 }
 
-func getPivot(slice []interface{}, vType string) interface{} {
-	for i := range slice {
-		if reflect.TypeOf(slice[i]).String() == vType {
-			return slice[i]
+func stateCommand(args []string) int {
+	flags := flag.NewFlagSet("state", flag.ExitOnError)
+	at := flags.String("at", "", "moment (RFC3339) to rebuild the account status at")
+	id := flags.String("account", "", "id of the account (default: operations without account-id)")
+	rulesPath := flags.String("rules", "", "json file of the rules the operations were decided with (default: built-in thresholds)")
+	inputFormat := flags.String("input-format", authorizer.FormatNDJSON, "format of the input: ndjson, csv or protobuf")
+	locationName := flags.String("location", "", "time zone transaction times are parsed in (default: Etc/GMT)")
+	_ = flags.Parse(args)
+
+	if *at == "" || flags.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "usage: authorize state --at <RFC3339> [--account <id>] [--rules <file>] [--input-format <format>] [--location <zone>] [operations file]")
+		return 2
+	}
+
+	moment, err := authorizer.ParseTime(*at)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	options := authorizer.Options{}
+	if err := loadRules(*rulesPath, &options); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	var location *time.Location
+	if *locationName != "" {
+		location, err = time.LoadLocation(*locationName)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
 		}
 	}
-	return nil
-}
 
-func hasHighFrequencySmallInterval(slice []interface{}, transaction Transaction) bool {
-	length := len(slice)
-	if length > 3 {
-		result := getPivot(slice, "main.AccountOperation")
-		if result != nil {
-			pivot := result.(AccountOperation).Account
-			if pivot.ActiveCard && pivot.AvailableLimit == 100 {
-				for count, i := 0, length-1; i >= 0; i-- {
-					if reflect.TypeOf(slice[i]).String() == "main.TransactionOperation" {
-						t1 := transaction.Time.(time.Time)
-						t2 := slice[i].(TransactionOperation).Transaction.Time.(time.Time)
-						if t1.Sub(t2).Minutes() < 2 {
-							count++
-							if count == 3 {
-								return true
-							}
-						}
-					}
-				}
-			}
+	input := os.Stdin
+	if flags.NArg() == 1 {
+		input, err = os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
 		}
+		defer input.Close()
 	}
-	return false
+
+	status, err := authorizer.StateAt(input, *id, moment, authorizer.StateOptions{
+		InputFormat: *inputFormat,
+		Rules:       options.Rules,
+		Location:    location,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	jsonData, _ := json.Marshal(status)
+	fmt.Println(string(jsonData))
+	return 0
 }